@@ -8,7 +8,6 @@ import (
 	"github.com/carapace-sh/carapace"
 	"github.com/spf13/cobra"
 	"github.com/steschwa/fq/firestore"
-	"github.com/steschwa/fq/firestore/parser"
 )
 
 var deleteCommand = &cobra.Command{
@@ -32,8 +31,14 @@ var deleteCommand = &cobra.Command{
 		}
 		defer client.Close()
 
-		deleteClient := firestore.NewDeleteClient(client, config.Path)
-		deleteClient.SetWheres(config.Wheres)
+		var deleteClient *firestore.DeleteClient
+		if config.Group != "" {
+			deleteClient = firestore.NewCollectionGroupDeleteClient(client, config.Group, config.Path)
+		} else {
+			deleteClient = firestore.NewDeleteClient(client, config.Path)
+		}
+		deleteClient.SetFilters(config.Filters)
+		deleteClient.SetPostFilters(config.PostFilters)
 		err = deleteClient.Exec(firestore.DeleteOptions{
 			ShowProgress: config.ShowProgress,
 			Delay:        config.Delay,
@@ -48,26 +53,34 @@ var deleteCommand = &cobra.Command{
 
 var (
 	deleteWhere        []string
+	deleteFilter       []string
+	deleteIDPrefix     string
+	deleteGroup        string
 	deleteShowProgress bool
 	deleteDelay        int
 )
 
 func init() {
-	deleteCommand.Flags().StringArrayVarP(&deleteWhere, "where", "w", nil, "documents filter in format {KEY} {OPERATOR} {VALUE}. can be used multiple times")
+	addWhereFlag(deleteCommand, &deleteWhere)
+	addPostFilterFlag(deleteCommand, &deleteFilter)
+	addIDPrefixFlag(deleteCommand, &deleteIDPrefix)
 	deleteCommand.Flags().BoolVar(&deleteShowProgress, "progress", false, "show the progress")
 	deleteCommand.Flags().IntVar(&deleteDelay, "delay", 0, "delay between operations in milliseconds")
 
 	addProjectFlag(deleteCommand)
-	addPathFlag(deleteCommand)
+	addPathOrGroupFlags(deleteCommand, &deleteGroup)
 
 	c := carapace.Gen(deleteCommand)
 	c.Standalone()
 }
 
 type DeleteConfig struct {
-	ProjectID    string
-	Path         string
-	Wheres       []firestore.Where
+	ProjectID string
+	Path      string
+	Group     string
+	Filters   []firestore.Filter
+	// PostFilters are checked on the client after --where
+	PostFilters  []firestore.PostFilter
 	ShowProgress bool
 	Delay        int
 }
@@ -81,20 +94,26 @@ func initDeleteConfig() (config DeleteConfig, err error) {
 	}
 	config.ProjectID = ProjectID
 
-	err = firestore.ValidatePath(Path)
+	err = validatePathOrGroup(Path, deleteGroup)
 	if err != nil {
-		return config, fmt.Errorf("invalid firestore path")
+		return config, err
 	}
 	config.Path = Path
+	config.Group = deleteGroup
 
-	config.Wheres = make([]firestore.Where, len(deleteWhere))
-	for i, wRaw := range deleteWhere {
-		w, err := parser.Parse(wRaw)
-		if err != nil {
-			return config, fmt.Errorf("failed to parse firestore where: %s", err.Error())
+	config.Filters, err = parseFilters(deleteWhere)
+	if err != nil {
+		return config, err
+	}
+	if deleteIDPrefix != "" {
+		if config.Group != "" {
+			return config, errIDPrefixWithGroup
 		}
-
-		config.Wheres[i] = w
+		config.Filters = append(config.Filters, firestore.IDPrefixFilter(deleteIDPrefix))
+	}
+	config.PostFilters, err = parsePostFilters(deleteFilter, config.Path, config.Group)
+	if err != nil {
+		return config, err
 	}
 
 	config.ShowProgress = deleteShowProgress
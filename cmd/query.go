@@ -1,14 +1,17 @@
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/spf13/cobra"
 	"github.com/steschwa/fq/firestore"
 	"github.com/steschwa/fq/firestore/parser"
+	"github.com/steschwa/fq/output"
+	"github.com/steschwa/fq/utils"
 )
 
 var queryCommand = &cobra.Command{
@@ -26,51 +29,208 @@ var queryCommand = &cobra.Command{
 		}
 		defer client.Close()
 
-		if firestore.IsCollectionPath(config.Path) {
-			queryClient := firestore.NewQueryClient(client, config.Path)
-			queryClient.SetWheres(config.Wheres).
-				SetOrderBy(config.OrderBy, firestore.GetFirestoreDirection(config.OrderDescending)).
-				SetLimit(config.Limit)
+		if config.Group != "" || firestore.IsCollectionPath(config.Path) {
+			var queryClient *firestore.QueryClient
+			if config.Group != "" {
+				queryClient = firestore.NewCollectionGroupQueryClient(client, config.Group, config.Path)
+			} else {
+				queryClient = firestore.NewQueryClient(client, config.Path)
+			}
+			queryClient.SetFilters(config.Filters).
+				SetPostFilters(config.PostFilters)
+			for _, o := range config.OrderBy {
+				queryClient.SetOrderBy(o.Path, o.Direction)
+			}
+			queryClient.SetLimit(config.Limit).
+				SetLimitToLast(config.LimitToLast).
+				SetOffset(config.Offset).
+				SetPageToken(config.PageToken)
+			for _, cursor := range config.Cursors {
+				queryClient.SetCursor(cursor)
+			}
+			if config.Select != nil {
+				queryClient.SetSelect(config.Select)
+			}
+
+			// compact json and plain counts don't end with a newline
+			lineOpen := false
+			if queryClient.HasPostFilters() {
+				defer func() {
+					if lineOpen {
+						fmt.Fprintln(os.Stderr)
+					}
+					fmt.Fprintln(os.Stderr, queryClient.PostFilterStats().String())
+				}()
+			}
+
+			if len(config.Grouping.Keys) > 0 {
+				result, err := queryClient.GroupBy(config.Grouping)
+				if err != nil {
+					return fmt.Errorf("grouping documents: %v", err)
+				}
+
+				lineOpen = config.Output == output.FormatJSON && !config.JSON.Pretty && config.JQ == nil
+				return printGroupResult(result, config)
+			}
+
+			if len(config.Aggregations) > 0 {
+				res, err := queryClient.GetAggregations(config.Aggregations)
+				if err != nil {
+					return fmt.Errorf("loading aggregations: %v", err)
+				}
+
+				if config.Output == output.FormatTable {
+					return printAggregationTable(res, config.Aggregations)
+				}
+				if config.Output == output.FormatYAML {
+					return output.WriteYAML(os.Stdout, res)
+				}
 
-			if config.Count {
+				err = printJSON(res, config)
+				if err != nil {
+					return fmt.Errorf("printing aggregations: %v", err)
+				}
+				lineOpen = !config.JSON.Pretty && config.JQ == nil
+			} else if config.Count {
 				count, err := queryClient.GetCount()
 				if err != nil {
 					return fmt.Errorf("loading documents count: %v", err)
 				}
 
+				if config.JQ != nil {
+					return printJSON(count, config)
+				}
+
 				fmt.Print(count)
+				lineOpen = true
+			} else if config.Template != nil {
+				pageToken, err := streamDocs(queryClient, config, func(doc *firestore.FirestoreDoc) error {
+					return config.Template.Execute(os.Stdout, doc.TemplateData())
+				})
+				if err != nil {
+					return fmt.Errorf("executing template: %v", err)
+				}
+
+				if pageToken != "" {
+					fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", pageToken)
+				}
+			} else if config.Output == output.FormatNDJSON {
+				pageToken, err := queryClient.Stream(config.DocOptions, func(doc *firestore.FirestoreDoc) error {
+					return printJSONLine(doc, config)
+				})
+				if err != nil {
+					return fmt.Errorf("streaming documents: %v", err)
+				}
+
+				if pageToken != "" {
+					fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", pageToken)
+				}
+			} else if config.Output == output.FormatTable {
+				docs, pageToken, err := queryClient.GetPage(config.DocOptions)
+				if err != nil {
+					return fmt.Errorf("loading documents: %v", err)
+				}
+
+				err = printTable(docs, config)
+				if err != nil {
+					return err
+				}
+
+				if pageToken != "" {
+					fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", pageToken)
+				}
+			} else if config.Output == output.FormatYAML {
+				docs, pageToken, err := queryClient.GetPage(config.DocOptions)
+				if err != nil {
+					return fmt.Errorf("loading documents: %v", err)
+				}
+
+				err = output.WriteYAML(os.Stdout, docs)
+				if err != nil {
+					return fmt.Errorf("writing yaml: %v", err)
+				}
+
+				if pageToken != "" {
+					fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", pageToken)
+				}
+			} else if config.Output.IsDelimited() {
+				docs, pageToken, err := queryClient.GetPage(config.DocOptions)
+				if err != nil {
+					return fmt.Errorf("loading documents: %v", err)
+				}
+
+				err = printDelimited(docs, config)
+				if err != nil {
+					return err
+				}
+
+				if pageToken != "" {
+					fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", pageToken)
+				}
 			} else {
-				docs, err := queryClient.GetDocs()
+				docs, pageToken, err := queryClient.GetPage(config.DocOptions)
 				if err != nil {
 					return fmt.Errorf("loading documents: %v", err)
 				}
 
-				j, err := json.Marshal(docs)
+				err = printJSON(docs, config)
 				if err != nil {
-					return fmt.Errorf("marshalling documents to json: %v", err)
+					return fmt.Errorf("printing documents: %v", err)
 				}
+				lineOpen = !config.JSON.Pretty && config.JQ == nil
 
-				fmt.Print(string(j))
+				if pageToken != "" {
+					if lineOpen {
+						fmt.Fprintln(os.Stderr)
+						lineOpen = false
+					}
+					fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", pageToken)
+				}
 			}
 
 		} else if firestore.IsDocumentPath(config.Path) {
 			docClient := firestore.NewDocClient(client, config.Path)
+			if config.Select != nil {
+				docClient.SetSelect(config.Select)
+			}
 
-			doc, err := docClient.GetDoc()
+			doc, err := docClient.GetDoc(config.DocOptions)
 			if errors.Is(err, firestore.ErrDocumentNotFound) {
-				fmt.Print("null")
-				return nil
+				if config.Template != nil {
+					return nil
+				}
+				return printJSON(nil, config)
 			}
 			if err != nil {
 				return fmt.Errorf("loading document: %v", err)
 			}
 
-			j, err := json.Marshal(doc)
-			if err != nil {
-				return fmt.Errorf("marshalling document to json: %v", err)
+			if config.Template != nil {
+				err = config.Template.Execute(os.Stdout, doc.TemplateData())
+				if err != nil {
+					return fmt.Errorf("executing template: %v", err)
+				}
+				return nil
+			}
+
+			if config.Output == output.FormatTable {
+				return printTable([]*firestore.FirestoreDoc{doc}, config)
+			}
+			if config.Output.IsDelimited() {
+				return printDelimited([]*firestore.FirestoreDoc{doc}, config)
+			}
+			if config.Output == output.FormatYAML {
+				return output.WriteYAML(os.Stdout, doc)
 			}
 
-			fmt.Print(string(j))
+			if config.Output == output.FormatNDJSON {
+				return printJSONLine(doc, config)
+			}
+
+			err = printJSON(doc, config)
+			if err != nil {
+				return fmt.Errorf("printing document: %v", err)
+			}
 		}
 
 		return nil
@@ -78,35 +238,136 @@ var queryCommand = &cobra.Command{
 }
 
 var (
-	count      bool
-	queryWhere []string
-	orderBy    string
-	desc       bool
-	limit      int
+	count         bool
+	queryWhere    []string
+	queryFilter   []string
+	queryIDPrefix string
+	queryGroup    string
+	orderBy       []string
+	desc          bool
+	limit         int
+	withMeta      bool
+	format        string
+	querySelect   []string
+	startAt       string
+	startAfter    string
+	endAt         string
+	endBefore     string
+	offset        int
+	limitToLast   int
+	pageToken     string
+	querySum      []string
+	queryAvg      []string
+	groupBy       []string
+	bucket        []string
+	queryOutput   string
+	flattenDepth  int
+	templateText  string
+	templateFile  string
+	pretty        bool
+	compact       bool
+	color         string
+	jqExpr        string
+	rawOutput     bool
+)
+
+var (
+	errLimitAndLimitToLast     = errors.New("--limit and --limit-to-last can't be used together")
+	errPageTokenWithStart      = errors.New("--page-token can't be used with --start-at or --start-after")
+	errPageTokenWithLast       = errors.New("--page-token can't be used with --limit-to-last")
+	errPageTokenWithOffset     = errors.New("--page-token can't be used with --offset. the token already continues after the skipped documents")
+	errLimitToLastWithoutOrder = errors.New("--limit-to-last requires --order-by")
+	errNDJSONWithLimitToLast   = errors.New("ndjson output can't be used with --limit-to-last")
+	errTemplateWithOutput      = errors.New("--template can't be used with --output")
+	errTemplateWithAggregation = errors.New("--template can't be used with --count, --sum, --avg, --group-by or --bucket")
+	errJQWithTemplate          = errors.New("--jq can't be used with --template")
+	errJQOutput                = errors.New("--jq can only be used with json or ndjson output")
+	errRawOutputWithoutJQ      = errors.New("--raw-output requires --jq")
+	errNegativeOffset          = errors.New("invalid offset value. can't be negative")
 )
 
 func init() {
 	queryCommand.Flags().BoolVar(&count, "count", false, "count documents instead of returning json")
-	queryCommand.Flags().StringArrayVarP(&queryWhere, "where", "w", nil, "documents filter in format {KEY} {OPERATOR} {VALUE}. can be used multiple times")
-	queryCommand.Flags().StringVar(&orderBy, "order-by", "", "set column to order by")
-	queryCommand.Flags().BoolVar(&desc, "desc", false, "order documents in descending order (only used if --order-by is set)")
+	queryCommand.Flags().StringArrayVar(&querySum, "sum", nil, "sum up a field instead of returning documents. can be used multiple times")
+	queryCommand.Flags().StringArrayVar(&queryAvg, "avg", nil, "average a field instead of returning documents. can be used multiple times")
+	addWhereFlag(queryCommand, &queryWhere)
+	addPostFilterFlag(queryCommand, &queryFilter)
+	addIDPrefixFlag(queryCommand, &queryIDPrefix)
+	queryCommand.Flags().StringArrayVar(&querySelect, "select", nil, "only return this field. can be used multiple times. an empty value returns document ids only")
+	queryCommand.Flags().StringArrayVar(&orderBy, "order-by", nil, "order by field in format {KEY}[:asc|desc]. can be used multiple times")
+	queryCommand.Flags().BoolVar(&desc, "desc", false, "order --order-by fields without direction in descending order")
 	queryCommand.Flags().IntVar(&limit, "limit", -1, "limit number of returned documents")
+	queryCommand.Flags().IntVar(&limitToLast, "limit-to-last", -1, "return the last number of documents. requires --order-by")
+	queryCommand.Flags().IntVar(&offset, "offset", 0, "skip number of documents")
+	queryCommand.Flags().StringVar(&startAt, "start-at", "", "start at order by values like '42, \"foo\"' or a document path")
+	queryCommand.Flags().StringVar(&startAfter, "start-after", "", "start after order by values or a document path")
+	queryCommand.Flags().StringVar(&endAt, "end-at", "", "end at order by values or a document path")
+	queryCommand.Flags().StringVar(&endBefore, "end-before", "", "end before order by values or a document path")
+	queryCommand.Flags().StringVar(&pageToken, "page-token", "", "continue after the page that printed this token")
+	queryCommand.Flags().BoolVar(&withMeta, "with-meta", false, "wrap documents in an envelope with id, path and timestamps")
+	queryCommand.Flags().StringVar(&format, "format", "json", "document encoding. one of json, typed")
+	queryCommand.Flags().StringArrayVar(&groupBy, "group-by", nil, "count documents per value of this field. can be used multiple times")
+	queryCommand.Flags().StringArrayVar(&bucket, "bucket", nil, "count documents per time bucket of a timestamp field in format {KEY}:{hour|day|week|month|year}. can be used multiple times")
+	queryCommand.Flags().StringVarP(&queryOutput, "output", "o", "", "output format. one of json, ndjson, table, csv, tsv, yaml. defaults to table in a terminal and json otherwise")
+	queryCommand.Flags().StringVar(&templateText, "template", "", "print every document with a go template like '{{.id}} {{.data.email}}'")
+	queryCommand.Flags().StringVar(&templateFile, "template-file", "", "print every document with the go template in this file")
+	queryCommand.MarkFlagsMutuallyExclusive("template", "template-file")
+	queryCommand.Flags().BoolVar(&pretty, "pretty", false, "indent json. default in a terminal")
+	queryCommand.Flags().BoolVar(&compact, "compact", false, "print json on a single line. default if piped")
+	queryCommand.MarkFlagsMutuallyExclusive("pretty", "compact")
+	queryCommand.Flags().StringVar(&color, "color", "auto", "color json. one of auto, always, never. auto colors in a terminal unless NO_COLOR is set")
+	queryCommand.Flags().StringVar(&jqExpr, "jq", "", "filter the json output with a jq expression. applied per document with ndjson output")
+	queryCommand.Flags().BoolVarP(&rawOutput, "raw-output", "r", false, "print strings returned by --jq without quotes")
+	queryCommand.Flags().IntVar(&flattenDepth, "flatten-depth", -1, "number of nested map levels flattened into dotted csv columns. deeper maps become json cells")
 
 	addProjectFlag(queryCommand)
-	addPathFlag(queryCommand)
+	addPathOrGroupFlags(queryCommand, &queryGroup)
 
 	c := carapace.Gen(queryCommand)
 	c.Standalone()
+	c.FlagCompletion(carapace.ActionMap{
+		"format":        actionFormats(),
+		"output":        actionOutputs(),
+		"color":         carapace.ActionValues("auto", "always", "never"),
+		"template-file": carapace.ActionFiles(),
+	})
 }
 
 type QueryConfig struct {
-	ProjectID       string
-	Path            string
-	Count           bool
-	Wheres          []firestore.Where
-	OrderBy         string
-	OrderDescending bool
-	Limit           int
+	ProjectID string
+	Path      string
+	Group     string
+	Count     bool
+	// Aggregations are only set if --sum or --avg are used.
+	// a single --count keeps printing the plain number
+	Aggregations []firestore.Aggregation
+	// Grouping counts documents client side if it has keys
+	Grouping firestore.GroupOptions
+	Output   output.Format
+	// FlattenDepth limits how many map levels are flattened into csv columns.
+	// negative values flatten all levels
+	FlattenDepth int
+	// JSON formats json and ndjson output
+	JSON output.JSONOptions
+	// JQ filters json and ndjson output if set
+	JQ *output.JQ
+	// RawOutput prints strings returned by JQ without quotes
+	RawOutput bool
+	// Template prints every document if set and replaces Output
+	Template *output.Template
+	Filters  []firestore.Filter
+	// PostFilters are checked on the client after Filters
+	PostFilters []firestore.PostFilter
+	OrderBy     []firestore.OrderBy
+	Limit       int
+	LimitToLast int
+	Offset      int
+	Cursors     []firestore.Cursor
+	PageToken   string
+	// Select holds the fields to return. nil returns all fields,
+	// an empty slice only the document ids
+	Select     []firestore.KeyPath
+	DocOptions firestore.DocOptions
 }
 
 func initQueryConfig() (config QueryConfig, err error) {
@@ -115,38 +376,510 @@ func initQueryConfig() (config QueryConfig, err error) {
 	}
 	config.ProjectID = ProjectID
 
-	err = firestore.ValidatePath(Path)
+	err = validatePathOrGroup(Path, queryGroup)
 	if err != nil {
-		return config, fmt.Errorf("invalid firestore path")
+		return config, err
 	}
 	config.Path = Path
+	config.Group = queryGroup
 
-	config.Wheres = make([]firestore.Where, len(queryWhere))
-	for i, wRaw := range queryWhere {
-		w, err := parser.Parse(wRaw)
+	config.Filters, err = parseFilters(queryWhere)
+	if err != nil {
+		return config, err
+	}
+	if queryIDPrefix != "" {
+		if config.Group != "" {
+			return config, errIDPrefixWithGroup
+		}
+		config.Filters = append(config.Filters, firestore.IDPrefixFilter(queryIDPrefix))
+	}
+	config.PostFilters, err = parsePostFilters(queryFilter, config.Path, config.Group)
+	if err != nil {
+		return config, err
+	}
+
+	config.Count = count
+	config.Grouping, err = parseGrouping(groupBy, bucket, querySum, queryAvg)
+	if err != nil {
+		return config, err
+	}
+	if len(config.Grouping.Keys) == 0 {
+		config.Aggregations, err = parseAggregations(count, querySum, queryAvg)
 		if err != nil {
-			return config, fmt.Errorf("failed to parse firestore where: %s", err.Error())
+			return config, err
 		}
+	}
 
-		config.Wheres[i] = w
+	rawFormat := queryOutput
+	if rawFormat == "" && jqExpr != "" {
+		rawFormat = output.FormatJSON.String()
+	}
+	config.Output, err = parseOutput(rawFormat)
+	if err != nil {
+		return config, err
+	}
+	config.FlattenDepth = flattenDepth
+	config.JSON, err = parseJSONOptions(pretty, compact, color)
+	if err != nil {
+		return config, err
+	}
+	if jqExpr != "" {
+		config.JQ, err = output.ParseJQ(jqExpr)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --jq: %v", err)
+		}
+		if config.Output != output.FormatJSON && config.Output != output.FormatNDJSON {
+			return config, errJQOutput
+		}
+	}
+	config.RawOutput = rawOutput
+	if config.RawOutput && config.JQ == nil {
+		return config, errRawOutputWithoutJQ
+	}
+	config.Template, err = parseTemplate(templateText, templateFile)
+	if err != nil {
+		return config, err
+	}
+	if config.Template != nil {
+		if config.JQ != nil {
+			return config, errJQWithTemplate
+		}
+		if queryOutput != "" {
+			return config, errTemplateWithOutput
+		}
+		if count || len(config.Aggregations) > 0 || len(config.Grouping.Keys) > 0 {
+			return config, errTemplateWithAggregation
+		}
+	}
+	for _, raw := range orderBy {
+		o, err := parser.ParseOrderBy(raw, desc)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --order-by: %v", err)
+		}
+		config.OrderBy = append(config.OrderBy, o)
+	}
+	err = firestore.ValidateOrderBy(config.Filters, config.OrderBy)
+	if err != nil {
+		return config, fmt.Errorf("invalid --order-by: %v", err)
 	}
 
-	config.Count = count
-	config.OrderBy = orderBy
-	config.OrderDescending = desc
 	config.Limit = limit
+	config.LimitToLast = limitToLast
+	if config.Limit > 0 && config.LimitToLast > 0 {
+		return config, errLimitAndLimitToLast
+	}
+	if config.LimitToLast > 0 && len(config.OrderBy) == 0 {
+		return config, errLimitToLastWithoutOrder
+	}
+	if config.Output == output.FormatNDJSON && config.LimitToLast > 0 {
+		return config, errNDJSONWithLimitToLast
+	}
+	if offset < 0 {
+		return config, errNegativeOffset
+	}
+	config.Offset = offset
+
+	rawCursors := []struct {
+		position firestore.CursorPosition
+		raw      string
+	}{
+		{position: firestore.StartAt, raw: startAt},
+		{position: firestore.StartAfter, raw: startAfter},
+		{position: firestore.EndAt, raw: endAt},
+		{position: firestore.EndBefore, raw: endBefore},
+	}
+	for _, c := range rawCursors {
+		if c.raw == "" {
+			continue
+		}
+
+		cursor, err := parseCursor(c.position, c.raw)
+		if err != nil {
+			return config, err
+		}
+		config.Cursors = append(config.Cursors, cursor)
+	}
+
+	config.PageToken = pageToken
+	if config.PageToken != "" {
+		if startAt != "" || startAfter != "" {
+			return config, errPageTokenWithStart
+		}
+		if config.LimitToLast > 0 {
+			return config, errPageTokenWithLast
+		}
+		if config.Offset > 0 {
+			return config, errPageTokenWithOffset
+		}
+	}
+
+	config.Select, err = parseSelect(querySelect)
+	if err != nil {
+		return config, err
+	}
+
+	f, err := firestore.ParseFormat(format)
+	if err != nil {
+		return config, err
+	}
+	config.DocOptions = firestore.DocOptions{
+		// documents without fields are only useful with their ids
+		WithMeta: withMeta || (config.Select != nil && len(config.Select) == 0),
+		Format:   f,
+	}
 
 	return config, nil
 }
 
+func parseAggregations(count bool, sums []string, avgs []string) ([]firestore.Aggregation, error) {
+	if len(sums) == 0 && len(avgs) == 0 {
+		return nil, nil
+	}
+
+	var aggregations []firestore.Aggregation
+	if count {
+		aggregations = append(aggregations, firestore.Aggregation{Kind: firestore.AggregateCount})
+	}
+
+	fields := []struct {
+		kind firestore.AggregationKind
+		raw  []string
+	}{
+		{kind: firestore.AggregateSum, raw: sums},
+		{kind: firestore.AggregateAvg, raw: avgs},
+	}
+	for _, f := range fields {
+		for _, raw := range f.raw {
+			field, err := parser.ParseKey(raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse --%s: %v", f.kind, err)
+			}
+
+			aggregations = append(aggregations, firestore.Aggregation{Kind: f.kind, Field: field})
+		}
+	}
+
+	return aggregations, nil
+}
+
+// parseGrouping parses the client side group keys. --sum and --avg
+// are computed per group then
+func parseGrouping(groupBy []string, buckets []string, sums []string, avgs []string) (firestore.GroupOptions, error) {
+	var options firestore.GroupOptions
+	if len(groupBy) == 0 && len(buckets) == 0 {
+		return options, nil
+	}
+
+	for _, raw := range groupBy {
+		field, err := parser.ParseKey(raw)
+		if err != nil {
+			return options, fmt.Errorf("failed to parse --group-by: %v", err)
+		}
+		options.Keys = append(options.Keys, firestore.GroupKey{Field: field})
+	}
+	for _, raw := range buckets {
+		key, err := parser.ParseBucket(raw)
+		if err != nil {
+			return options, fmt.Errorf("failed to parse --bucket: %v", err)
+		}
+		options.Keys = append(options.Keys, key)
+	}
+
+	for _, raw := range sums {
+		field, err := parser.ParseKey(raw)
+		if err != nil {
+			return options, fmt.Errorf("failed to parse --sum: %v", err)
+		}
+		options.Sums = append(options.Sums, field)
+	}
+	for _, raw := range avgs {
+		field, err := parser.ParseKey(raw)
+		if err != nil {
+			return options, fmt.Errorf("failed to parse --avg: %v", err)
+		}
+		options.Avgs = append(options.Avgs, field)
+	}
+
+	return options, nil
+}
+
+func printGroupResult(result firestore.GroupResult, config QueryConfig) error {
+	switch config.Output {
+	case output.FormatTable:
+		return output.WriteTable(os.Stdout, result.Columns, result.Rows, utils.StdoutWidth())
+	case output.FormatCSV, output.FormatTSV:
+		return output.WriteCSV(os.Stdout, config.Output.Comma(), result.Columns, result.Rows)
+	case output.FormatYAML:
+		return output.WriteYAML(os.Stdout, result.Objects())
+	case output.FormatNDJSON:
+		for _, obj := range result.Objects() {
+			err := printJSONLine(obj, config)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := printJSON(result.Objects(), config)
+	if err != nil {
+		return fmt.Errorf("printing groups: %v", err)
+	}
+
+	return nil
+}
+
+// printJSON prints v as compact json or formatted for the terminal.
+// with --jq the results of the expression are printed instead.
+// output that doesn't fit on the screen is paged
+func printJSON(v any, config QueryConfig) error {
+	b, err := encodeJSON(v, config.JSON, config)
+	if err != nil {
+		return err
+	}
+
+	return utils.Page(b)
+}
+
+// printJSONLine prints v as a single line of json. it is only colored, never indented
+func printJSONLine(v any, config QueryConfig) error {
+	b, err := encodeJSON(v, output.JSONOptions{Color: config.JSON.Color}, config)
+	if err != nil {
+		return err
+	}
+
+	if config.JQ == nil {
+		b = append(b, '\n')
+	}
+
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+func encodeJSON(v any, options output.JSONOptions, config QueryConfig) ([]byte, error) {
+	if config.JQ == nil {
+		return output.EncodeJSON(v, options)
+	}
+
+	results, err := config.JQ.Run(v)
+	if err != nil {
+		return nil, fmt.Errorf("running --jq: %v", err)
+	}
+
+	return output.EncodeJQResults(results, options, config.RawOutput)
+}
+
+// parseJSONOptions indents and colors json if stdout is a terminal.
+// --pretty, --compact and --color override it, NO_COLOR disables colors for auto
+func parseJSONOptions(pretty bool, compact bool, color string) (output.JSONOptions, error) {
+	terminal := utils.IsStdoutTerminal()
+	options := output.JSONOptions{
+		Pretty: (terminal || pretty) && !compact,
+	}
+
+	switch color {
+	case "", "auto":
+		options.Color = terminal && os.Getenv("NO_COLOR") == ""
+	case "always":
+		options.Color = true
+	case "never":
+		options.Color = false
+	default:
+		return options, fmt.Errorf("unknown color %s. use one of auto, always, never", color)
+	}
+
+	return options, nil
+}
+
+// parseOutput parses --output. without a value documents are printed
+// as table for humans and as json for other programs
+func parseOutput(raw string) (output.Format, error) {
+	if raw == "" && utils.IsStdoutTerminal() {
+		return output.FormatTable, nil
+	}
+
+	return output.ParseFormat(raw)
+}
+
+// parseTemplate parses the --template or the content of --template-file
+func parseTemplate(text string, path string) (*output.Template, error) {
+	flag := "template"
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("file %s does not exist", path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("file %s can't be opened for reading", path)
+		}
+
+		flag = "template-file"
+		text = string(b)
+	}
+
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := output.ParseTemplate(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --%s: %v", flag, err)
+	}
+
+	return tmpl, nil
+}
+
+// streamDocs calls fn for every document as soon as it is loaded.
+// --limit-to-last can't be streamed, so the documents are loaded at once
+func streamDocs(queryClient *firestore.QueryClient, config QueryConfig, fn func(doc *firestore.FirestoreDoc) error) (string, error) {
+	if config.LimitToLast <= 0 {
+		return queryClient.Stream(config.DocOptions, fn)
+	}
+
+	docs, pageToken, err := queryClient.GetPage(config.DocOptions)
+	if err != nil {
+		return "", err
+	}
+
+	for _, doc := range docs {
+		err = fn(doc)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return pageToken, nil
+}
+
+// printTable prints docs as aligned columns truncated to the terminal width
+func printTable(docs []*firestore.FirestoreDoc, config QueryConfig) error {
+	columns := firestore.TableColumns(docs, config.Select, config.DocOptions.WithMeta)
+
+	rows := make([][]any, len(docs))
+	for i, doc := range docs {
+		rows[i] = doc.TableRow(columns, config.DocOptions.WithMeta)
+	}
+
+	return output.WriteTable(os.Stdout, columns, rows, utils.StdoutWidth())
+}
+
+// printAggregationTable prints the aggregations as a single row in the order they were requested
+func printAggregationTable(res map[string]any, aggregations []firestore.Aggregation) error {
+	columns := make([]string, len(aggregations))
+	row := make([]any, len(aggregations))
+	for i, a := range aggregations {
+		columns[i] = a.Alias()
+		row[i] = res[a.Alias()]
+	}
+
+	return output.WriteTable(os.Stdout, columns, [][]any{row}, utils.StdoutWidth())
+}
+
+// printDelimited prints docs as csv or tsv. the columns are the selected
+// fields or the union of the flattened fields of all docs
+func printDelimited(docs []*firestore.FirestoreDoc, config QueryConfig) error {
+	var columns []string
+	if config.Select != nil {
+		columns = firestore.SelectColumns(docs, config.Select, config.DocOptions.WithMeta)
+	} else {
+		columns = firestore.FlatColumns(docs, config.FlattenDepth)
+	}
+
+	rows := make([][]any, len(docs))
+	for i, doc := range docs {
+		rows[i] = doc.Row(columns, config.FlattenDepth)
+	}
+
+	err := output.WriteCSV(os.Stdout, config.Output.Comma(), columns, rows)
+	if err != nil {
+		return fmt.Errorf("writing %s: %v", config.Output, err)
+	}
+
+	return nil
+}
+
+// parseCursor parses a document path or comma separated order by values
+func parseCursor(position firestore.CursorPosition, raw string) (firestore.Cursor, error) {
+	cursor := firestore.Cursor{Position: position}
+
+	if !strings.ContainsAny(raw, "\"' ,") && firestore.IsDocumentPath(raw) {
+		cursor.DocPath = raw
+		return cursor, nil
+	}
+
+	values, err := parser.ParseValues(raw)
+	if err != nil {
+		return cursor, fmt.Errorf("failed to parse --%s: %v", position, err)
+	}
+	cursor.Values = values
+
+	return cursor, nil
+}
+
+// parseSelect parses the --select fields. empty values are skipped so
+// an empty value selects no fields at all, e.g.
+//
+//	--select ''
+func parseSelect(rawFields []string) ([]firestore.KeyPath, error) {
+	if rawFields == nil {
+		return nil, nil
+	}
+
+	fields := make([]firestore.KeyPath, 0, len(rawFields))
+	for _, raw := range rawFields {
+		if raw == "" {
+			continue
+		}
+
+		field, err := parser.ParseKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse --select: %v", err)
+		}
+		if field == firestore.DocumentID {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
 func (c QueryConfig) DebugPrint() {
 	fmt.Printf("ProjectID: %s\n", c.ProjectID)
 	fmt.Printf("Path: %s\n", c.Path)
+	fmt.Printf("Group: %s\n", c.Group)
 	fmt.Printf("Count: %t\n", c.Count)
-	for i, w := range c.Wheres {
-		fmt.Printf("Where (%d): %s\n", i+1, w.String())
+	for _, key := range c.Grouping.Keys {
+		fmt.Printf("Group-By: %s\n", key.String())
+	}
+	fmt.Printf("Output: %s\n", c.Output)
+	fmt.Printf("Flatten Depth: %d\n", c.FlattenDepth)
+	fmt.Printf("Pretty: %t\n", c.JSON.Pretty)
+	fmt.Printf("Color: %t\n", c.JSON.Color)
+	fmt.Printf("Template: %t\n", c.Template != nil)
+	fmt.Printf("JQ: %s\n", jqExpr)
+	fmt.Printf("Raw Output: %t\n", c.RawOutput)
+	for _, a := range c.Aggregations {
+		fmt.Printf("Aggregation: %s\n", a.Alias())
+	}
+	for i, f := range c.Filters {
+		fmt.Printf("Where (%d): %s\n", i+1, f.String())
+	}
+	for i, f := range c.PostFilters {
+		fmt.Printf("Filter (%d): %s\n", i+1, f.String())
+	}
+	for i, o := range c.OrderBy {
+		fmt.Printf("Order-By (%d): %s\n", i+1, o.String())
 	}
-	fmt.Printf("Order-By: %s\n", c.OrderBy)
-	fmt.Printf("Order Descending: %t\n", c.OrderDescending)
 	fmt.Printf("Limit: %d\n", c.Limit)
+	fmt.Printf("Limit To Last: %d\n", c.LimitToLast)
+	fmt.Printf("Offset: %d\n", c.Offset)
+	for _, cursor := range c.Cursors {
+		fmt.Printf("Cursor: %s\n", cursor.String())
+	}
+	fmt.Printf("Page Token: %s\n", c.PageToken)
+	fmt.Printf("Select: %v\n", c.Select)
+	fmt.Printf("With Meta: %t\n", c.DocOptions.WithMeta)
+	fmt.Printf("Format: %s\n", c.DocOptions.Format)
 }
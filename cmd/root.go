@@ -8,6 +8,8 @@ import (
 	"github.com/carapace-sh/carapace"
 	"github.com/spf13/cobra"
 	"github.com/steschwa/fq/completion"
+	"github.com/steschwa/fq/firestore"
+	"github.com/steschwa/fq/firestore/parser"
 )
 
 var (
@@ -30,12 +32,15 @@ var (
 )
 
 var (
-	errEmptyProjectID = errors.New("empty project id")
+	errEmptyProjectID    = errors.New("empty project id")
+	errIDPrefixWithGroup = errors.New("--id-prefix can't be used with --group")
+	errFilterOnDocument  = errors.New("--filter can only be used with collections")
 )
 
 func init() {
 	rootCmd.AddCommand(queryCommand)
 	rootCmd.AddCommand(setCommand)
+	rootCmd.AddCommand(updateCommand)
 	rootCmd.AddCommand(deleteCommand)
 
 	carapace.Gen(rootCmd).Standalone()
@@ -55,6 +60,99 @@ func addPathFlag(cmd *cobra.Command) {
 	cmd.MarkFlagRequired("path")
 }
 
+// addPathOrGroupFlags adds --path and --group of which one is required.
+// with --group, --path is the optional parent document of the collection group
+func addPathOrGroupFlags(cmd *cobra.Command, group *string) {
+	cmd.Flags().StringVar(&Path, "path", "", "collection or document path. parent document path if --group is set")
+	cmd.Flags().StringVar(group, "group", "", "collection id to query across all parents (collection group)")
+	cmd.MarkFlagsOneRequired("path", "group")
+}
+
+// validatePathOrGroup validates the --path and --group flags
+func validatePathOrGroup(path, group string) error {
+	if group != "" {
+		err := firestore.ValidateCollectionGroup(group, path)
+		if err != nil {
+			return fmt.Errorf("invalid collection group: %v", err)
+		}
+		return nil
+	}
+
+	err := firestore.ValidatePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid firestore path")
+	}
+
+	return nil
+}
+
+func addWhereFlag(cmd *cobra.Command, p *[]string) {
+	cmd.Flags().StringArrayVarP(p, "where", "w", nil, "documents filter in format {KEY} {OPERATOR} {VALUE}. conditions can be combined with && and ||. can be used multiple times")
+}
+
+func addIDPrefixFlag(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "id-prefix", "", "only include documents with an id starting with this prefix")
+}
+
+func addPostFilterFlag(cmd *cobra.Command, p *[]string) {
+	cmd.Flags().StringArrayVar(p, "filter", nil, "client-side filter applied after --where, e.g. 'email =~ /@example\\.com$/' or 'missing(deletedAt)'. can be used multiple times")
+}
+
+func parseFilters(rawFilters []string) ([]firestore.Filter, error) {
+	filters := make([]firestore.Filter, len(rawFilters))
+	for i, raw := range rawFilters {
+		filter, err := parser.ParseFilter(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse firestore where: %s", err.Error())
+		}
+
+		filters[i] = filter
+	}
+
+	return filters, nil
+}
+
+// parsePostFilters parses the client-side --filter expressions. they
+// can't be used on a single document
+func parsePostFilters(rawFilters []string, path string, group string) ([]firestore.PostFilter, error) {
+	if len(rawFilters) == 0 {
+		return nil, nil
+	}
+	if group == "" && firestore.IsDocumentPath(path) {
+		return nil, errFilterOnDocument
+	}
+
+	filters := make([]firestore.PostFilter, len(rawFilters))
+	for i, raw := range rawFilters {
+		filter, err := parser.ParsePostFilter(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse --filter: %s", err.Error())
+		}
+
+		filters[i] = filter
+	}
+
+	return filters, nil
+}
+
+func actionFormats() carapace.Action {
+	return carapace.ActionValuesDescribed(
+		"json", "plain json",
+		"typed", "json with firestore value types",
+	)
+}
+
+func actionOutputs() carapace.Action {
+	return carapace.ActionValuesDescribed(
+		"json", "json array",
+		"ndjson", "one json document per line, streamed",
+		"table", "aligned columns",
+		"csv", "comma separated values",
+		"tsv", "tab separated values",
+		"yaml", "yaml with tags for timestamps, references and geopoints",
+	)
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -1,15 +1,17 @@
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/spf13/cobra"
 	"github.com/steschwa/fq/firestore"
+	"github.com/steschwa/fq/firestore/parser"
 	"github.com/steschwa/fq/utils"
 )
 
@@ -62,13 +64,23 @@ var (
 	replaceDoc      bool
 	setShowProgress bool
 	setDelay        int
+	setFormat       string
+	intFields       []string
+	doubleFields    []string
+	inputFormat     string
+	setWithMeta     bool
 )
 
 func init() {
-	setCommand.Flags().StringVar(&dataPath, "data", "-", "input data json file. can be - to read from stdin")
+	setCommand.Flags().StringVar(&dataPath, "data", "-", "input data json or yaml file. can be - to read from stdin")
 	setCommand.Flags().BoolVar(&replaceDoc, "replace", false, "replace documents instead of merging")
 	setCommand.Flags().BoolVar(&setShowProgress, "progress", false, "show the progress")
 	setCommand.Flags().IntVar(&setDelay, "delay", 0, "delay between operations in milliseconds")
+	setCommand.Flags().StringVar(&setFormat, "format", "json", "input data encoding. one of json, typed")
+	setCommand.Flags().StringVar(&inputFormat, "input-format", "", "input data syntax. one of json, yaml. defaults to the file extension or json")
+	setCommand.Flags().BoolVar(&setWithMeta, "with-meta", false, "input documents are wrapped in the envelope written by query --with-meta")
+	setCommand.Flags().StringArrayVar(&intFields, "int-field", nil, "always write numbers at this field path as integers. can be used multiple times")
+	setCommand.Flags().StringArrayVar(&doubleFields, "double-field", nil, "always write numbers at this field path as doubles. can be used multiple times")
 
 	addProjectFlag(setCommand)
 	addPathFlag(setCommand)
@@ -76,7 +88,9 @@ func init() {
 	c := carapace.Gen(setCommand)
 	c.Standalone()
 	c.FlagCompletion(carapace.ActionMap{
-		"data": carapace.ActionFiles("json"),
+		"data":         carapace.ActionFiles("json", "yaml", "yml"),
+		"format":       actionFormats(),
+		"input-format": carapace.ActionValues("json", "yaml"),
 	})
 }
 
@@ -117,6 +131,35 @@ func initSetConfig() (config SetConfig, err error) {
 	}
 	config.Delay = setDelay
 
+	format, err := firestore.ParseFormat(setFormat)
+	if err != nil {
+		return config, err
+	}
+	decodeOptions := firestore.DecodeOptions{
+		Format:      format,
+		NumberTypes: make(map[firestore.KeyPath]firestore.NumberType),
+		Envelopes:   setWithMeta,
+	}
+	for _, field := range intFields {
+		key, err := parser.ParseKey(field)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --int-field: %v", err)
+		}
+
+		decodeOptions.NumberTypes[key] = firestore.NumberInt
+	}
+	for _, field := range doubleFields {
+		key, err := parser.ParseKey(field)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --double-field: %v", err)
+		}
+
+		if _, found := decodeOptions.NumberTypes[key]; found {
+			return config, fmt.Errorf("field %s can't be forced to int and double at the same time", field)
+		}
+		decodeOptions.NumberTypes[key] = firestore.NumberDouble
+	}
+
 	var (
 		r            io.Reader
 		dataPathName string
@@ -140,15 +183,48 @@ func initSetConfig() (config SetConfig, err error) {
 		}
 	}
 
+	syntax, err := parseInputFormat(inputFormat, dataPath)
+	if err != nil {
+		return config, err
+	}
+
 	if firestore.IsDocumentPath(config.Path) {
-		if err := json.NewDecoder(r).Decode(&config.DocumentData); err != nil {
-			return config, fmt.Errorf("failed to decode json from %s: %v", dataPathName, err)
+		if syntax == "yaml" {
+			config.DocumentData, err = firestore.DecodeYAMLObject(r, decodeOptions)
+		} else {
+			config.DocumentData, err = firestore.DecodeJSONObject(r, decodeOptions)
+		}
+		if err != nil {
+			return config, fmt.Errorf("failed to decode %s from %s: %v", syntax, dataPathName, err)
 		}
 	} else if firestore.IsCollectionPath(config.Path) {
-		if err := json.NewDecoder(r).Decode(&config.CollectionData); err != nil {
-			return config, fmt.Errorf("failed to decode json from %s: %v", dataPathName, err)
+		if syntax == "yaml" {
+			config.CollectionData, err = firestore.DecodeYAMLArray(r, decodeOptions)
+		} else {
+			config.CollectionData, err = firestore.DecodeJSONArray(r, decodeOptions)
+		}
+		if err != nil {
+			return config, fmt.Errorf("failed to decode %s from %s: %v", syntax, dataPathName, err)
 		}
 	}
 
 	return config, nil
 }
+
+// parseInputFormat returns json or yaml. without --input-format
+// .yaml and .yml files are yaml, everything else json
+func parseInputFormat(raw string, path string) (string, error) {
+	if raw != "" {
+		if raw != "json" && raw != "yaml" {
+			return "", fmt.Errorf("unknown input format %s", raw)
+		}
+		return raw, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	default:
+		return "json", nil
+	}
+}
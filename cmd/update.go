@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+	"github.com/steschwa/fq/firestore"
+	"github.com/steschwa/fq/firestore/parser"
+)
+
+var updateCommand = &cobra.Command{
+	Use:   "update",
+	Short: "update fields of firestore documents",
+	RunE: func(*cobra.Command, []string) error {
+		config, err := initUpdateConfig()
+		if errors.Is(err, errNonEmulatorProjectID) {
+			fmt.Println("only emulator projects are supported (projects starting with demo-*).")
+			fmt.Println("see https://firebase.google.com/docs/emulator-suite/connect_firestore#choose_a_firebase_project")
+			os.Exit(1)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		client, err := firestore.NewClient(config.ProjectID)
+		if err != nil {
+			return fmt.Errorf("failed to create firestore client: %v", err)
+		}
+		defer client.Close()
+
+		updateClient := firestore.NewUpdateClient(client, config.Path)
+		updateClient.SetFilters(config.Filters)
+		err = updateClient.Exec(config.Updates, firestore.UpdateOptions{
+			ShowProgress: config.ShowProgress,
+			Delay:        config.Delay,
+		})
+		if err != nil {
+			return fmt.Errorf("updating documents: %v", err)
+		}
+
+		return nil
+	},
+}
+
+var (
+	updateWhere        []string
+	updateSet          []string
+	updateUnset        []string
+	updateInc          []string
+	updateServerTime   []string
+	updateShowProgress bool
+	updateDelay        int
+)
+
+var (
+	errNoUpdates = errors.New("no updates. use at least one of --set, --unset, --inc or --server-time")
+)
+
+func init() {
+	addWhereFlag(updateCommand, &updateWhere)
+	updateCommand.Flags().StringArrayVar(&updateSet, "set", nil, "set a field in format {KEY}={VALUE}. can be used multiple times")
+	updateCommand.Flags().StringArrayVar(&updateUnset, "unset", nil, "delete a field. can be used multiple times")
+	updateCommand.Flags().StringArrayVar(&updateInc, "inc", nil, "increment a field in format {KEY}={NUMBER}. can be used multiple times")
+	updateCommand.Flags().StringArrayVar(&updateServerTime, "server-time", nil, "set a field to the server timestamp. can be used multiple times")
+	updateCommand.Flags().BoolVar(&updateShowProgress, "progress", false, "show the progress")
+	updateCommand.Flags().IntVar(&updateDelay, "delay", 0, "delay between operations in milliseconds")
+
+	addProjectFlag(updateCommand)
+	addPathFlag(updateCommand)
+
+	c := carapace.Gen(updateCommand)
+	c.Standalone()
+}
+
+type UpdateConfig struct {
+	ProjectID    string
+	Path         string
+	Filters      []firestore.Filter
+	Updates      []firestore.FieldUpdate
+	ShowProgress bool
+	Delay        int
+}
+
+func initUpdateConfig() (config UpdateConfig, err error) {
+	if ProjectID == "" {
+		return config, errEmptyProjectID
+	}
+	if !firestore.IsEmulatorProject(ProjectID) {
+		return config, errNonEmulatorProjectID
+	}
+	config.ProjectID = ProjectID
+
+	err = firestore.ValidatePath(Path)
+	if err != nil {
+		return config, fmt.Errorf("invalid firestore path")
+	}
+	config.Path = Path
+
+	config.Filters, err = parseFilters(updateWhere)
+	if err != nil {
+		return config, err
+	}
+
+	for _, raw := range updateSet {
+		key, value, err := parser.ParseAssignment(raw)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --set: %v", err)
+		}
+
+		config.Updates = append(config.Updates, firestore.FieldUpdate{Path: key, Operation: firestore.UpdateSet, Value: value})
+	}
+	for _, raw := range updateUnset {
+		key, err := parser.ParseKey(raw)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --unset: %v", err)
+		}
+
+		config.Updates = append(config.Updates, firestore.FieldUpdate{Path: key, Operation: firestore.UpdateDelete})
+	}
+	for _, raw := range updateInc {
+		key, value, err := parser.ParseAssignment(raw)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --inc: %v", err)
+		}
+
+		config.Updates = append(config.Updates, firestore.FieldUpdate{Path: key, Operation: firestore.UpdateIncrement, Value: value})
+	}
+	for _, raw := range updateServerTime {
+		key, err := parser.ParseKey(raw)
+		if err != nil {
+			return config, fmt.Errorf("failed to parse --server-time: %v", err)
+		}
+
+		config.Updates = append(config.Updates, firestore.FieldUpdate{Path: key, Operation: firestore.UpdateServerTimestamp})
+	}
+
+	if len(config.Updates) == 0 {
+		return config, errNoUpdates
+	}
+
+	config.ShowProgress = updateShowProgress
+
+	if updateDelay < 0 {
+		return config, errNegativeDelay
+	}
+	config.Delay = updateDelay
+
+	return config, nil
+}
@@ -0,0 +1,178 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+type (
+	AggregationKind int
+
+	// Aggregation is computed by firestore over all documents of a query
+	Aggregation struct {
+		Kind AggregationKind
+		// Field is the summed or averaged field. unused for counts
+		Field KeyPath
+	}
+)
+
+const (
+	AggregateCount AggregationKind = iota + 1
+	AggregateSum
+	AggregateAvg
+)
+
+func (k AggregationKind) String() string {
+	switch k {
+	case AggregateCount:
+		return "count"
+	case AggregateSum:
+		return "sum"
+	case AggregateAvg:
+		return "avg"
+	default:
+		return ""
+	}
+}
+
+// Alias is the key of the aggregation result, e.g. count or sum_total
+func (a Aggregation) Alias() string {
+	if a.Kind == AggregateCount {
+		return a.Kind.String()
+	}
+
+	return fmt.Sprintf("%s_%s", a.Kind, a.Field)
+}
+
+// GetAggregations runs all aggregations in a single request.
+// the results are keyed by the aggregation alias
+func (b QueryClient) GetAggregations(aggregations []Aggregation) (map[string]any, error) {
+	var fields []KeyPath
+	for _, a := range aggregations {
+		if a.Kind != AggregateCount {
+			fields = append(fields, a.Field)
+		}
+	}
+	b.selectAlso(fields)
+
+	if b.countsOnClient() {
+		return b.clientAggregations(aggregations)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	query, err := b.build(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// aliases sent to firestore have to be valid field names,
+	// so they are mapped to the readable ones afterwards
+	aggr := query.NewAggregationQuery()
+	for i, a := range aggregations {
+		alias := fmt.Sprintf("a%d", i)
+		switch a.Kind {
+		case AggregateCount:
+			aggr = aggr.WithCount(alias)
+		case AggregateSum:
+			aggr = aggr.WithSumPath(a.Field.FieldPath(), alias)
+		case AggregateAvg:
+			aggr = aggr.WithAvgPath(a.Field.FieldPath(), alias)
+		default:
+			return nil, fmt.Errorf("invalid aggregation %d", a.Kind)
+		}
+	}
+
+	res, err := aggr.Get(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil, fmt.Errorf("getting aggregations timed out")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]any, len(aggregations))
+	for i, a := range aggregations {
+		value, ok := res[fmt.Sprintf("a%d", i)]
+		if !ok {
+			return nil, fmt.Errorf("missing '%s' in response", a.Alias())
+		}
+
+		v, err := aggregationValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("converting %s: %v", a.Alias(), err)
+		}
+		out[a.Alias()] = v
+	}
+
+	return out, nil
+}
+
+// clientAggregations computes the aggregations while streaming the documents,
+// because firestore can't apply post filters or merge split queries
+func (b QueryClient) clientAggregations(aggregations []Aggregation) (map[string]any, error) {
+	var count int64
+	sums := make([]numberSum, len(aggregations))
+
+	_, err := b.streamSnapshots(func(snapshot *firestore.DocumentSnapshot) error {
+		data := snapshot.Data()
+
+		count++
+		for i, a := range aggregations {
+			if a.Kind != AggregateCount {
+				sums[i].add(data, a.Field)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]any, len(aggregations))
+	for i, a := range aggregations {
+		switch a.Kind {
+		case AggregateCount:
+			out[a.Alias()] = count
+		case AggregateSum:
+			out[a.Alias()] = sums[i].sum()
+		case AggregateAvg:
+			out[a.Alias()] = sums[i].avg()
+		default:
+			return nil, fmt.Errorf("invalid aggregation %d", a.Kind)
+		}
+	}
+
+	return out, nil
+}
+
+// aggregationValue converts an aggregation result to an int64, a float64 or nil.
+// sums are integers as long as all summed values are integers and averages
+// of no documents are null
+func aggregationValue(value any) (any, error) {
+	v, ok := value.(*firestorepb.Value)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %T", value)
+	}
+
+	switch v := v.GetValueType().(type) {
+	case *firestorepb.Value_IntegerValue:
+		return v.IntegerValue, nil
+	case *firestorepb.Value_DoubleValue:
+		if math.IsNaN(v.DoubleValue) || math.IsInf(v.DoubleValue, 0) {
+			return typedDoubleValue(v.DoubleValue), nil
+		}
+		return v.DoubleValue, nil
+	case *firestorepb.Value_NullValue:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected value type %T", v)
+	}
+}
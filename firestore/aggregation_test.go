@@ -0,0 +1,45 @@
+package firestore
+
+import (
+	"math"
+	"testing"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestAggregationAlias(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("count", Aggregation{Kind: AggregateCount}.Alias())
+	assert.Equal("sum_total", Aggregation{Kind: AggregateSum, Field: "total"}.Alias())
+	assert.Equal("avg_price.net", Aggregation{Kind: AggregateAvg, Field: "price.net"}.Alias())
+}
+
+func TestAggregationValue(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		value any
+
+		expected any
+	}{
+		{value: &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: 42}}, expected: int64(42)},
+		{value: &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: 2.5}}, expected: 2.5},
+		{value: &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: math.NaN()}}, expected: "NaN"},
+		{value: &firestorepb.Value{ValueType: &firestorepb.Value_NullValue{NullValue: structpb.NullValue_NULL_VALUE}}, expected: nil},
+	}
+
+	for _, fixture := range fixtures {
+		v, err := aggregationValue(fixture.value)
+		assert.NoError(err)
+		assert.Equal(fixture.expected, v)
+	}
+
+	_, err := aggregationValue(int64(1))
+	assert.Error(err)
+
+	_, err = aggregationValue(&firestorepb.Value{ValueType: &firestorepb.Value_StringValue{StringValue: "1"}})
+	assert.Error(err)
+}
@@ -0,0 +1,120 @@
+package firestore
+
+import (
+	"bytes"
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// compareValues orders field values like firestore does. values of different
+// types are ordered by type: null, booleans, numbers, timestamps, strings,
+// bytes, references, geopoints, arrays and maps.
+// see https://firebase.google.com/docs/firestore/manage-data/data-types#value_type_ordering
+func compareValues(a, b any) int {
+	rankA, rankB := valueRank(a), valueRank(b)
+	if rankA != rankB {
+		return cmp.Compare(rankA, rankB)
+	}
+
+	switch a := a.(type) {
+	case bool:
+		return cmp.Compare(boolRank(a), boolRank(b.(bool)))
+	case int64, float64:
+		return compareNumbers(a, b)
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	case []byte:
+		return bytes.Compare(a, b.([]byte))
+	case *firestore.DocumentRef:
+		return slices.Compare(strings.Split(a.Path, "/"), strings.Split(b.(*firestore.DocumentRef).Path, "/"))
+	case *latlng.LatLng:
+		b := b.(*latlng.LatLng)
+		if c := cmp.Compare(a.GetLatitude(), b.GetLatitude()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.GetLongitude(), b.GetLongitude())
+	case []any:
+		return slices.CompareFunc(a, b.([]any), compareValues)
+	case map[string]any:
+		return compareMaps(a, b.(map[string]any))
+	default:
+		return 0
+	}
+}
+
+func valueRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case time.Time:
+		return 3
+	case string:
+		return 4
+	case []byte:
+		return 5
+	case *firestore.DocumentRef:
+		return 6
+	case *latlng.LatLng:
+		return 7
+	case []any:
+		return 8
+	case map[string]any:
+		return 9
+	default:
+		return 10
+	}
+}
+
+// compareNumbers compares integers exactly. NaN is smaller than all other numbers
+func compareNumbers(a, b any) int {
+	ai, aIsInt := a.(int64)
+	bi, bIsInt := b.(int64)
+	if aIsInt && bIsInt {
+		return cmp.Compare(ai, bi)
+	}
+
+	fa, fb := toFloat(a), toFloat(b)
+	if math.IsNaN(fa) || math.IsNaN(fb) {
+		return cmp.Compare(boolRank(!math.IsNaN(fa)), boolRank(!math.IsNaN(fb)))
+	}
+	return cmp.Compare(fa, fb)
+}
+
+// compareMaps compares the sorted keys and their values pairwise
+func compareMaps(a, b map[string]any) int {
+	keysA := sortedKeys(a)
+	keysB := sortedKeys(b)
+
+	for i := range min(len(keysA), len(keysB)) {
+		if c := strings.Compare(keysA[i], keysB[i]); c != 0 {
+			return c
+		}
+		if c := compareValues(a[keysA[i]], b[keysB[i]]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(keysA), len(keysB))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
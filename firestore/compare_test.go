@@ -0,0 +1,53 @@
+package firestore
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestCompareValues(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+
+	// every value is smaller than the next one
+	ordered := []any{
+		nil,
+		false,
+		true,
+		math.NaN(),
+		int64(-1),
+		0.5,
+		int64(1),
+		now,
+		now.Add(time.Second),
+		"",
+		"a",
+		"b",
+		[]byte("a"),
+		&firestore.DocumentRef{Path: "users/a"},
+		&firestore.DocumentRef{Path: "users/a/posts/a"},
+		&firestore.DocumentRef{Path: "users/b"},
+		&latlng.LatLng{Latitude: 1, Longitude: 2},
+		&latlng.LatLng{Latitude: 1, Longitude: 3},
+		[]any{int64(1)},
+		[]any{int64(1), "a"},
+		[]any{int64(2)},
+		map[string]any{"a": int64(1)},
+		map[string]any{"a": int64(2)},
+		map[string]any{"b": int64(1)},
+	}
+
+	for i := 0; i+1 < len(ordered); i++ {
+		assert.Equal(-1, compareValues(ordered[i], ordered[i+1]), "%v < %v", ordered[i], ordered[i+1])
+		assert.Equal(1, compareValues(ordered[i+1], ordered[i]), "%v > %v", ordered[i+1], ordered[i])
+	}
+
+	assert.Equal(0, compareValues(int64(1), 1.0))
+	assert.Equal(0, compareValues(map[string]any{"a": "x"}, map[string]any{"a": "x"}))
+}
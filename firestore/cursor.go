@@ -0,0 +1,173 @@
+package firestore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	CursorPosition int
+
+	// Cursor positions a query at values of its order by fields or at a document
+	Cursor struct {
+		Position CursorPosition
+		Values   []Value
+		// DocPath positions the cursor at this document instead of Values
+		DocPath string
+	}
+
+	// pageToken holds the cursor values of the last document of a page
+	pageToken struct {
+		After []any `json:"after"`
+	}
+)
+
+const (
+	StartAt CursorPosition = iota + 1
+	StartAfter
+	EndAt
+	EndBefore
+)
+
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+)
+
+func (p CursorPosition) String() string {
+	switch p {
+	case StartAt:
+		return "start-at"
+	case StartAfter:
+		return "start-after"
+	case EndAt:
+		return "end-at"
+	case EndBefore:
+		return "end-before"
+	default:
+		return ""
+	}
+}
+
+func (c Cursor) String() string {
+	if c.DocPath != "" {
+		return fmt.Sprintf("%s %s", c.Position, c.DocPath)
+	}
+
+	return fmt.Sprintf("%s %s", c.Position, ArrayValue{Values: c.Values}.String())
+}
+
+func (c Cursor) apply(ctx context.Context, client *firestore.Client, q firestore.Query) (firestore.Query, error) {
+	var args []any
+	if c.DocPath != "" {
+		doc := client.Doc(c.DocPath)
+		if doc == nil {
+			return q, fmt.Errorf("invalid cursor document path %s", c.DocPath)
+		}
+
+		snapshot, err := doc.Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return q, fmt.Errorf("cursor document %s does not exist", c.DocPath)
+		}
+		if err != nil {
+			return q, fmt.Errorf("loading cursor document: %v", err)
+		}
+
+		args = append(args, snapshot)
+	} else {
+		for _, v := range c.Values {
+			args = append(args, bindValue(client, v).Value())
+		}
+	}
+
+	switch c.Position {
+	case StartAt:
+		return q.StartAt(args...), nil
+	case StartAfter:
+		return q.StartAfter(args...), nil
+	case EndAt:
+		return q.EndAt(args...), nil
+	case EndBefore:
+		return q.EndBefore(args...), nil
+	default:
+		return q, fmt.Errorf("invalid cursor position %d", c.Position)
+	}
+}
+
+// cursorValues returns the values of fields of snapshot.
+// the document id is returned as reference
+func cursorValues(snapshot *firestore.DocumentSnapshot, fields []KeyPath) ([]any, error) {
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if field == DocumentID {
+			values[i] = snapshot.Ref
+			continue
+		}
+
+		v, err := snapshot.DataAtPath(field.FieldPath())
+		if err != nil {
+			return nil, fmt.Errorf("missing order by field %s in document %s", field, relativePath(snapshot.Ref.Path))
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// encodePageToken returns an opaque token holding the cursor values
+func encodePageToken(values []any) (string, error) {
+	typed := make([]any, len(values))
+	for i, value := range values {
+		v, err := toTypedValue(value)
+		if err != nil {
+			return "", fmt.Errorf("cursor value %d: %v", i, err)
+		}
+		typed[i] = v
+	}
+
+	b, err := json.Marshal(pageToken{After: typed})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodePageToken returns the cursor values stored in token
+func decodePageToken(client *firestore.Client, token string) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	var t pageToken
+	err = json.Unmarshal(b, &t)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	values := make([]any, len(t.After))
+	for i, typed := range t.After {
+		v, err := fromTypedValue(typed)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", ErrInvalidPageToken, err)
+		}
+
+		if ref, ok := v.(RefPath); ok {
+			doc := client.Doc(string(ref))
+			if doc == nil {
+				return nil, fmt.Errorf("%v: invalid document path %s", ErrInvalidPageToken, ref)
+			}
+			v = doc
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,212 @@
+package firestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPageToken(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, err := encodePageToken([]any{int64(42), 2.5, "foo", createdAt, client.Doc("users/abc")})
+	assert.NoError(err)
+	assert.NotContains(token, "=")
+
+	values, err := decodePageToken(client, token)
+	assert.NoError(err)
+	if assert.Len(values, 5) {
+		assert.Equal(int64(42), values[0])
+		assert.Equal(2.5, values[1])
+		assert.Equal("foo", values[2])
+		assert.True(createdAt.Equal(values[3].(time.Time)))
+		assert.Equal(client.Doc("users/abc").Path, values[4].(*firestore.DocumentRef).Path)
+	}
+
+	_, err = decodePageToken(client, "not a token")
+	assert.ErrorIs(err, ErrInvalidPageToken)
+}
+
+func TestQueryClientPageToken(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	token, err := encodePageToken([]any{int64(42), client.Doc("users/abc")})
+	assert.NoError(err)
+
+	q, err := NewQueryClient(client, "users").
+		SetOrderBy("age", firestore.Desc).
+		SetLimit(10).
+		SetPageToken(token).
+		build(context.Background(), nil)
+	assert.NoError(err)
+
+	b, err := q.Serialize()
+	assert.NoError(err)
+
+	var req firestorepb.RunQueryRequest
+	assert.NoError(proto.Unmarshal(b, &req))
+
+	orderBy := req.GetStructuredQuery().GetOrderBy()
+	if assert.Len(orderBy, 2) {
+		assert.Equal("age", orderBy[0].GetField().GetFieldPath())
+		assert.Equal(string(DocumentID), orderBy[1].GetField().GetFieldPath())
+		assert.Equal(firestorepb.StructuredQuery_DESCENDING, orderBy[1].GetDirection())
+	}
+
+	startAt := req.GetStructuredQuery().GetStartAt()
+	assert.False(startAt.GetBefore())
+	if assert.Len(startAt.GetValues(), 2) {
+		assert.Equal(int64(42), startAt.GetValues()[0].GetIntegerValue())
+		assert.Equal(client.Doc("users/abc").Path, startAt.GetValues()[1].GetReferenceValue())
+	}
+
+	// the token holds one value more than the order by fields
+	_, err = NewQueryClient(client, "users").SetPageToken(token).build(context.Background(), nil)
+	assert.ErrorContains(err, ErrInvalidPageToken.Error())
+}
+
+func TestQueryClientPageTokenImplicitOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	token, err := encodePageToken([]any{int64(42), client.Doc("users/abc")})
+	assert.NoError(err)
+
+	fixtures := []Filter{
+		Where{Key: "age", Operator: Gt, Value: NewIntValue(5)},
+		AndFilter{Filters: []Filter{
+			Where{Key: "status", Operator: Eq, Value: NewStringValue("open")},
+			Where{Key: "age", Operator: Gt, Value: NewIntValue(5)},
+		}},
+	}
+
+	for i, filter := range fixtures {
+		q, err := NewQueryClient(client, "users").
+			SetFilters([]Filter{filter}).
+			SetLimit(10).
+			SetPageToken(token).
+			build(context.Background(), nil)
+		if !assert.NoError(err, "index %d", i) {
+			continue
+		}
+
+		b, err := q.Serialize()
+		assert.NoError(err)
+
+		var req firestorepb.RunQueryRequest
+		assert.NoError(proto.Unmarshal(b, &req))
+
+		orderBy := req.GetStructuredQuery().GetOrderBy()
+		if assert.Len(orderBy, 2, "index %d", i) {
+			assert.Equal("age", orderBy[0].GetField().GetFieldPath(), "index %d", i)
+			assert.Equal(firestorepb.StructuredQuery_ASCENDING, orderBy[0].GetDirection(), "index %d", i)
+			assert.Equal(string(DocumentID), orderBy[1].GetField().GetFieldPath(), "index %d", i)
+		}
+		assert.Len(req.GetStructuredQuery().GetStartAt().GetValues(), 2, "index %d", i)
+	}
+}
+
+func TestQueryClientOrders(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	fixtures := []struct {
+		client   *QueryClient
+		expected []orderField
+	}{
+		{
+			client:   NewQueryClient(client, "users"),
+			expected: []orderField{{path: DocumentID, dir: firestore.Asc}},
+		},
+		{
+			client: NewQueryClient(client, "users").
+				SetFilters([]Filter{Where{Key: "status", Operator: In, Value: ArrayValue{Values: []Value{NewStringValue("open")}}}}),
+			expected: []orderField{{path: DocumentID, dir: firestore.Asc}},
+		},
+		{
+			client: NewQueryClient(client, "users").
+				SetFilters([]Filter{Where{Key: "score", Operator: Lt, Value: NewIntValue(1)}, Where{Key: "age", Operator: Neq, Value: NewIntValue(5)}}),
+			expected: []orderField{{path: "age", dir: firestore.Asc}, {path: "score", dir: firestore.Asc}, {path: DocumentID, dir: firestore.Asc}},
+		},
+		{
+			client: NewQueryClient(client, "users").
+				SetFilters([]Filter{Where{Key: "age", Operator: Gt, Value: NewIntValue(5)}}).
+				SetOrderBy("age", firestore.Desc),
+			expected: []orderField{{path: "age", dir: firestore.Desc}, {path: DocumentID, dir: firestore.Desc}},
+		},
+		{
+			client: NewQueryClient(client, "users").
+				SetOrderBy(DocumentID, firestore.Desc).
+				SetOrderBy("age", firestore.Asc),
+			expected: []orderField{{path: DocumentID, dir: firestore.Desc}, {path: "age", dir: firestore.Asc}},
+		},
+	}
+
+	for i, fixture := range fixtures {
+		assert.Equal(fixture.expected, fixture.client.orders(), "index %d", i)
+	}
+}
+
+func TestQueryClientValueCursors(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	q, err := NewQueryClient(client, "users").
+		SetOrderBy("age", firestore.Asc).
+		SetCursor(Cursor{Position: StartAt, Values: []Value{NewIntValue(18)}}).
+		SetCursor(Cursor{Position: EndBefore, Values: []Value{NewIntValue(30)}}).
+		SetOffset(5).
+		build(context.Background(), nil)
+	assert.NoError(err)
+
+	b, err := q.Serialize()
+	assert.NoError(err)
+
+	var req firestorepb.RunQueryRequest
+	assert.NoError(proto.Unmarshal(b, &req))
+
+	query := req.GetStructuredQuery()
+	assert.True(query.GetStartAt().GetBefore())
+	assert.Equal(int64(18), query.GetStartAt().GetValues()[0].GetIntegerValue())
+	assert.True(query.GetEndAt().GetBefore())
+	assert.Equal(int64(30), query.GetEndAt().GetValues()[0].GetIntegerValue())
+	assert.Equal(int32(5), query.GetOffset())
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -12,9 +14,14 @@ import (
 
 type (
 	DeleteClient struct {
-		client *firestore.Client
-		path   string
-		wheres []Where
+		client  *firestore.Client
+		path    string
+		filters []Filter
+		// postFilters are checked on the client after loading the documents
+		postFilters []PostFilter
+		// group is the collection id of a collection group delete.
+		// path is its optional parent document then
+		group string
 	}
 
 	DeleteOptions struct {
@@ -30,15 +37,29 @@ func NewDeleteClient(client *firestore.Client, path string) *DeleteClient {
 	}
 }
 
-func (c *DeleteClient) SetWheres(wheres []Where) {
-	c.wheres = wheres
+// NewCollectionGroupDeleteClient deletes documents of all collections with the
+// id collectionID. if parent is set only collections below that document are included
+func NewCollectionGroupDeleteClient(client *firestore.Client, collectionID, parent string) *DeleteClient {
+	return &DeleteClient{
+		client: client,
+		path:   parent,
+		group:  collectionID,
+	}
+}
+
+func (c *DeleteClient) SetFilters(filters []Filter) {
+	c.filters = filters
+}
+
+func (c *DeleteClient) SetPostFilters(filters []PostFilter) {
+	c.postFilters = filters
 }
 
 func (c DeleteClient) Exec(options DeleteOptions) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
 	defer cancel()
 
-	if IsCollectionPath(c.path) {
+	if c.group != "" || IsCollectionPath(c.path) {
 		return c.deleteMany(ctx, options)
 	} else if IsDocumentPath(c.path) {
 		return c.deleteOne(ctx, options)
@@ -48,13 +69,24 @@ func (c DeleteClient) Exec(options DeleteOptions) error {
 }
 
 func (c DeleteClient) deleteMany(ctx context.Context, options DeleteOptions) error {
-	q := c.client.Collection(c.path).Query
-	for _, where := range c.wheres {
-		q = q.Where(string(where.Key), where.Operator.String(), where.Value.Value())
+	// filters with too many values are split into several queries
+	sets, postFilters, err := splitFilters(c.filters)
+	if err != nil {
+		return err
 	}
+	postFilters = append(postFilters, c.postFilters...)
 
-	iter := q.Documents(ctx)
-	snapshots, err := iter.GetAll()
+	queries := make([]firestore.Query, len(sets))
+	for i, set := range sets {
+		queries[i], err = c.query(set)
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshots, err := loadSplitQueries(queries, func(q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+		return q.Documents(ctx).GetAll()
+	})
 	if errors.Is(err, context.Canceled) {
 		return fmt.Errorf("loading document timed out")
 	}
@@ -62,6 +94,15 @@ func (c DeleteClient) deleteMany(ctx context.Context, options DeleteOptions) err
 		return fmt.Errorf("loading document: %v", err)
 	}
 
+	if len(postFilters) > 0 {
+		scanned := len(snapshots)
+		snapshots = slices.DeleteFunc(snapshots, func(snapshot *firestore.DocumentSnapshot) bool {
+			return !snapshot.Exists() || !MatchAll(postFilters, snapshot.Data())
+		})
+		stats := PostFilterStats{Scanned: scanned, Matched: len(snapshots)}
+		fmt.Fprintln(os.Stderr, stats.String())
+	}
+
 	if len(snapshots) == 0 {
 		fmt.Println("no documents to delete")
 		return nil
@@ -93,6 +134,19 @@ func (c DeleteClient) deleteMany(ctx context.Context, options DeleteOptions) err
 	return nil
 }
 
+func (c DeleteClient) query(filters []Filter) (firestore.Query, error) {
+	if c.group == "" {
+		return applyFilters(c.client, c.path, c.client.Collection(c.path).Query, filters)
+	}
+
+	q, err := collectionGroupQuery(c.client, c.group, c.path)
+	if err != nil {
+		return q, err
+	}
+
+	return applyFilters(c.client, "", q, filters)
+}
+
 func (c DeleteClient) deleteOne(ctx context.Context, options DeleteOptions) error {
 	_, err := c.client.Doc(c.path).Delete(ctx)
 	if errors.Is(err, context.Canceled) {
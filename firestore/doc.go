@@ -3,43 +3,127 @@ package firestore
 import (
 	"encoding/json"
 	"math"
+	"time"
+
+	"cloud.google.com/go/firestore"
 )
 
-type FirestoreDoc struct {
-	Value map[string]any
-}
+type (
+	FirestoreDoc struct {
+		Value   map[string]any
+		Meta    *DocMeta
+		options DocOptions
+	}
+
+	DocMeta struct {
+		ID         string    `json:"id"`
+		Path       string    `json:"path"`
+		CreateTime time.Time `json:"createTime"`
+		UpdateTime time.Time `json:"updateTime"`
+		ReadTime   time.Time `json:"readTime"`
+	}
+
+	DocOptions struct {
+		// WithMeta wraps the document data in an envelope that also
+		// carries the document id, path and timestamps
+		WithMeta bool
+		Format   Format
+	}
+
+	docEnvelope struct {
+		DocMeta
+		Data map[string]any `json:"data"`
+	}
+)
 
 var _ json.Marshaler = &FirestoreDoc{}
 
 func NewFirestoreDoc(value map[string]any) *FirestoreDoc {
-	prepareMap(value)
-	return &FirestoreDoc{value}
+	return &FirestoreDoc{Value: value}
+}
+
+func newFirestoreDocFromSnapshot(snapshot *firestore.DocumentSnapshot, options DocOptions) *FirestoreDoc {
+	doc := NewFirestoreDoc(snapshot.Data())
+	doc.Meta = &DocMeta{
+		ID:         snapshot.Ref.ID,
+		Path:       relativePath(snapshot.Ref.Path),
+		CreateTime: snapshot.CreateTime,
+		UpdateTime: snapshot.UpdateTime,
+		ReadTime:   snapshot.ReadTime,
+	}
+	doc.options = options
+
+	return doc
 }
 
 func (d *FirestoreDoc) MarshalJSON() ([]byte, error) {
-	return json.Marshal(d.Value)
+	data, err := d.encodeValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.options.WithMeta && d.Meta != nil {
+		return json.Marshal(docEnvelope{
+			DocMeta: *d.Meta,
+			Data:    data,
+		})
+	}
+
+	return json.Marshal(data)
+}
+
+func (d *FirestoreDoc) encodeValue() (map[string]any, error) {
+	if d.options.Format == FormatTyped {
+		return toTypedFields(d.Value)
+	}
+
+	return plainMap(d.Value), nil
 }
 
-func prepareMap(v map[string]any) {
+// plainMap returns a copy of v that can be encoded with encoding/json
+func plainMap(v map[string]any) map[string]any {
+	out := make(map[string]any, len(v))
 	for key, value := range v {
-		switch value := value.(type) {
-		case float64:
-			if math.IsNaN(value) {
-				v[key] = "NaN"
-			}
-		case []any:
-			prepareSlice(value)
-		case map[string]any:
-			prepareMap(value)
+		out[key] = plainValue(value)
+	}
+
+	return out
+}
+
+func plainValue(value any) any {
+	switch value := value.(type) {
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return typedDoubleValue(value)
+		}
+		return value
+	case *firestore.DocumentRef:
+		return relativePath(value.Path)
+	case []any:
+		out := make([]any, len(value))
+		for i, v := range value {
+			out[i] = plainValue(v)
 		}
+		return out
+	case map[string]any:
+		return plainMap(value)
+	default:
+		return value
 	}
 }
 
-func prepareSlice(v []any) {
-	for _, value := range v {
-		switch value := value.(type) {
-		case map[string]any:
-			prepareMap(value)
+// TemplateData returns the document in the shape of the meta envelope
+// independent of DocOptions, so templates can always use .id and .data
+func (d *FirestoreDoc) TemplateData() map[string]any {
+	data := map[string]any{
+		dataColumn: plainMap(d.Value),
+	}
+
+	if d.Meta != nil {
+		for _, column := range metaColumns {
+			data[column] = d.metaValue(column)
 		}
 	}
+
+	return data
 }
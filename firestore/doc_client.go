@@ -17,6 +17,8 @@ var (
 
 type DocClient struct {
 	doc *firestore.DocumentRef
+	// selection holds the fields to return. nil returns all fields
+	selection []KeyPath
 }
 
 func NewDocClient(client *firestore.Client, path string) *DocClient {
@@ -25,7 +27,18 @@ func NewDocClient(client *firestore.Client, path string) *DocClient {
 	}
 }
 
-func (l DocClient) GetDoc() (*FirestoreDoc, error) {
+// SetSelect only returns the fields at paths.
+// without paths only the document meta data is returned
+func (l *DocClient) SetSelect(paths []KeyPath) *DocClient {
+	l.selection = paths
+	if l.selection == nil {
+		l.selection = make([]KeyPath, 0)
+	}
+
+	return l
+}
+
+func (l DocClient) GetDoc(options DocOptions) (*FirestoreDoc, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
 	defer cancel()
 
@@ -40,5 +53,11 @@ func (l DocClient) GetDoc() (*FirestoreDoc, error) {
 		return nil, err
 	}
 
-	return NewFirestoreDoc(snapshot.Data()), nil
+	doc := newFirestoreDocFromSnapshot(snapshot, options)
+	// single document reads can't be projected by firestore
+	if l.selection != nil {
+		doc.Value = selectFields(doc.Value, l.selection)
+	}
+
+	return doc, nil
 }
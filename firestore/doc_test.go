@@ -0,0 +1,62 @@
+package firestore
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirestoreDocMarshalling(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := NewFirestoreDoc(map[string]any{"foo": "bar"})
+	doc.Meta = &DocMeta{
+		ID:         "abc",
+		Path:       "users/abc",
+		CreateTime: ts,
+		UpdateTime: ts,
+		ReadTime:   ts,
+	}
+
+	j, err := json.Marshal(doc)
+	assert.NoError(err)
+	assert.JSONEq(`{"foo": "bar"}`, string(j))
+
+	doc.options.WithMeta = true
+	j, err = json.Marshal(doc)
+	assert.NoError(err)
+	assert.JSONEq(`{
+		"id": "abc",
+		"path": "users/abc",
+		"createTime": "2025-01-01T00:00:00Z",
+		"updateTime": "2025-01-01T00:00:00Z",
+		"readTime": "2025-01-01T00:00:00Z",
+		"data": {"foo": "bar"}
+	}`, string(j))
+
+	obj, err := DecodeJSONObject(bytes.NewReader(j), DecodeOptions{Envelopes: true})
+	assert.NoError(err)
+	assert.Equal(doc.Value, obj.Value)
+}
+
+func TestFirestoreDocTemplateData(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := NewFirestoreDoc(map[string]any{"email": "alice@example.com"})
+	assert.Equal(map[string]any{"data": map[string]any{"email": "alice@example.com"}}, doc.TemplateData())
+
+	doc.Meta = &DocMeta{ID: "abc", Path: "users/abc", CreateTime: ts, UpdateTime: ts, ReadTime: ts}
+	assert.Equal(map[string]any{
+		"id":         "abc",
+		"path":       "users/abc",
+		"createTime": ts,
+		"updateTime": ts,
+		"readTime":   ts,
+		"data":       map[string]any{"email": "alice@example.com"},
+	}, doc.TemplateData())
+}
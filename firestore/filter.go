@@ -0,0 +1,200 @@
+package firestore
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"cloud.google.com/go/firestore"
+)
+
+type (
+	// Filter is a where condition or a boolean combination of them
+	Filter interface {
+		String() string
+		EntityFilter() firestore.EntityFilter
+	}
+
+	AndFilter struct {
+		Filters []Filter
+	}
+	OrFilter struct {
+		Filters []Filter
+	}
+)
+
+var (
+	_ Filter = Where{}
+	_ Filter = AndFilter{}
+	_ Filter = OrFilter{}
+)
+
+func (f AndFilter) EntityFilter() firestore.EntityFilter {
+	return firestore.AndFilter{Filters: entityFilters(f.Filters)}
+}
+func (f AndFilter) String() string {
+	return joinFilters(f.Filters, " && ")
+}
+
+func (f OrFilter) EntityFilter() firestore.EntityFilter {
+	return firestore.OrFilter{Filters: entityFilters(f.Filters)}
+}
+func (f OrFilter) String() string {
+	return joinFilters(f.Filters, " || ")
+}
+
+func entityFilters(filters []Filter) []firestore.EntityFilter {
+	out := make([]firestore.EntityFilter, len(filters))
+	for i, filter := range filters {
+		out[i] = filter.EntityFilter()
+	}
+
+	return out
+}
+
+func joinFilters(filters []Filter, sep string) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		switch filter.(type) {
+		case AndFilter, OrFilter:
+			parts[i] = fmt.Sprintf("(%s)", filter.String())
+		default:
+			parts[i] = filter.String()
+		}
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// bindFilter binds all values of filter to client. document ids compared
+// with DocumentID are resolved relative to the collection at path. path is
+// empty for collection groups, which only accept full document paths
+func bindFilter(client *firestore.Client, path string, filter Filter) (Filter, error) {
+	switch filter := filter.(type) {
+	case Where:
+		if filter.Key == DocumentID {
+			value, err := documentIDValue(path, filter.Value)
+			if err != nil {
+				return nil, err
+			}
+			filter.Value = value
+		}
+
+		filter.Value = bindValue(client, filter.Value)
+		return filter, nil
+	case AndFilter:
+		filters, err := bindFilters(client, path, filter.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return AndFilter{Filters: filters}, nil
+	case OrFilter:
+		filters, err := bindFilters(client, path, filter.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return OrFilter{Filters: filters}, nil
+	default:
+		return filter, nil
+	}
+}
+
+func bindFilters(client *firestore.Client, path string, filters []Filter) ([]Filter, error) {
+	out := make([]Filter, len(filters))
+	for i, filter := range filters {
+		bound, err := bindFilter(client, path, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = bound
+	}
+
+	return out, nil
+}
+
+// documentIDValue converts document ids and paths to references.
+// ids without a slash are relative to the collection at path. the same id
+// can exist in every collection of a collection group, so they are
+// rejected if path is empty
+func documentIDValue(path string, value Value) (Value, error) {
+	switch value := value.(type) {
+	case ReferenceValue:
+		return value, nil
+	case StringValue:
+		docPath := value.value
+		if !strings.Contains(docPath, "/") {
+			if path == "" {
+				return nil, fmt.Errorf("%s of collection groups must be compared with full document paths, got id %s", DocumentID, docPath)
+			}
+			docPath = path + "/" + docPath
+		}
+
+		if !IsDocumentPath(docPath) {
+			return nil, fmt.Errorf("invalid document id or path %s", value.value)
+		}
+
+		return NewReferenceValue(docPath), nil
+	case ArrayValue:
+		out := NewArrayValue()
+		for _, v := range value.Values {
+			ref, err := documentIDValue(path, v)
+			if err != nil {
+				return nil, err
+			}
+			out.Add(ref)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%s must be compared with document ids or paths, got %s", DocumentID, value.String())
+	}
+}
+
+// IDPrefixFilter matches all documents with an id starting with prefix
+func IDPrefixFilter(prefix string) Filter {
+	lower := Where{Key: DocumentID, Operator: Gte, Value: NewStringValue(prefix)}
+
+	upper, ok := prefixUpperBound(prefix)
+	if !ok {
+		return lower
+	}
+
+	return AndFilter{Filters: []Filter{
+		lower,
+		Where{Key: DocumentID, Operator: Lt, Value: NewStringValue(upper)},
+	}}
+}
+
+// prefixUpperBound returns the smallest string greater than all strings
+// starting with prefix. prefixes made up of unicode.MaxRune only have none
+func prefixUpperBound(prefix string) (string, bool) {
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		switch runes[i] {
+		case unicode.MaxRune:
+			continue
+		case '\uD7FF':
+			// surrogates can't be encoded, the next rune follows them
+			runes[i] = '\uE000'
+		default:
+			runes[i]++
+		}
+
+		return string(runes[:i+1]), true
+	}
+
+	return "", false
+}
+
+func applyFilters(client *firestore.Client, path string, q firestore.Query, filters []Filter) (firestore.Query, error) {
+	bound, err := bindFilters(client, path, filters)
+	if err != nil {
+		return q, err
+	}
+
+	for _, filter := range bound {
+		q = q.WhereEntity(filter.EntityFilter())
+	}
+
+	return q, nil
+}
@@ -0,0 +1,142 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterEntityFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := AndFilter{Filters: []Filter{
+		Where{Key: "status", Operator: Eq, Value: NewStringValue("open")},
+		OrFilter{Filters: []Filter{
+			Where{Key: "meta.priority", Operator: Gt, Value: NewIntValue(3)},
+			Where{Key: "assignee", Operator: Eq, Value: NewNullValue()},
+		}},
+	}}
+
+	assert.Equal(firestore.AndFilter{Filters: []firestore.EntityFilter{
+		firestore.PropertyPathFilter{Path: firestore.FieldPath{"status"}, Operator: "==", Value: "open"},
+		firestore.OrFilter{Filters: []firestore.EntityFilter{
+			firestore.PropertyPathFilter{Path: firestore.FieldPath{"meta", "priority"}, Operator: ">", Value: 3},
+			firestore.PropertyPathFilter{Path: firestore.FieldPath{"assignee"}, Operator: "==", Value: nil},
+		}},
+	}}, filter.EntityFilter())
+
+	assert.Equal(`status == "open" && (meta.priority > 3 || assignee == null)`, filter.String())
+}
+
+func TestBindFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	assert.NoError(err)
+	defer client.Close()
+
+	filter := OrFilter{Filters: []Filter{
+		Where{Key: "author", Operator: Eq, Value: NewReferenceValue("users/abc")},
+		Where{Key: "reviewer", Operator: In, Value: ArrayValue{Values: []Value{NewReferenceValue("users/def")}}},
+	}}
+
+	boundFilter, err := bindFilter(client, "posts", filter)
+	assert.NoError(err)
+	bound := boundFilter.(OrFilter)
+
+	ref := bound.Filters[0].(Where).Value.Value().(*firestore.DocumentRef)
+	assert.Equal("projects/demo-test/databases/(default)/documents/users/abc", ref.Path)
+
+	refs := bound.Filters[1].(Where).Value.Value().([]any)
+	assert.Equal("projects/demo-test/databases/(default)/documents/users/def", refs[0].(*firestore.DocumentRef).Path)
+}
+
+func TestBindDocumentIDFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	assert.NoError(err)
+	defer client.Close()
+
+	filter := AndFilter{Filters: []Filter{
+		Where{Key: DocumentID, Operator: In, Value: ArrayValue{Values: []Value{
+			NewStringValue("abc"),
+			NewStringValue("users/abc/posts/def"),
+			NewReferenceValue("users/abc/posts/ghi"),
+		}}},
+	}}
+
+	bound, err := bindFilter(client, "users/abc/posts", filter)
+	assert.NoError(err)
+
+	refs := bound.(AndFilter).Filters[0].(Where).Value.Value().([]any)
+	assert.Len(refs, 3)
+	assert.Equal("projects/demo-test/databases/(default)/documents/users/abc/posts/abc", refs[0].(*firestore.DocumentRef).Path)
+	assert.Equal("projects/demo-test/databases/(default)/documents/users/abc/posts/def", refs[1].(*firestore.DocumentRef).Path)
+	assert.Equal("projects/demo-test/databases/(default)/documents/users/abc/posts/ghi", refs[2].(*firestore.DocumentRef).Path)
+
+	_, err = bindFilter(client, "users", Where{Key: DocumentID, Operator: Eq, Value: NewIntValue(1)})
+	assert.Error(err)
+
+	_, err = bindFilter(client, "users", Where{Key: DocumentID, Operator: Eq, Value: NewStringValue("users/abc/posts")})
+	assert.Error(err)
+
+	// collection groups
+	bound, err = bindFilter(client, "", Where{Key: DocumentID, Operator: Eq, Value: NewStringValue("users/abc/orders/def")})
+	assert.NoError(err)
+	assert.Equal("projects/demo-test/databases/(default)/documents/users/abc/orders/def", bound.(Where).Value.Value().(*firestore.DocumentRef).Path)
+
+	_, err = bindFilter(client, "", Where{Key: DocumentID, Operator: In, Value: ArrayValue{Values: []Value{
+		NewStringValue("users/abc/orders/def"),
+		NewStringValue("def"),
+	}}})
+	assert.EqualError(err, "__name__ of collection groups must be compared with full document paths, got id def")
+}
+
+func TestIDPrefixFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		prefix string
+
+		expected string
+	}{
+		{prefix: "abc", expected: `__name__ >= "abc" && __name__ < "abd"`},
+		{prefix: "a", expected: `__name__ >= "a" && __name__ < "b"`},
+		{prefix: "a\U0010FFFF", expected: "__name__ >= \"a\U0010FFFF\" && __name__ < \"b\""},
+		// nothing sorts after the largest rune, so there is no upper bound
+		{prefix: "\U0010FFFF", expected: "__name__ >= \"\U0010FFFF\""},
+	}
+
+	for i, fixture := range fixtures {
+		assert.Equal(fixture.expected, IDPrefixFilter(fixture.prefix).String(), i)
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		prefix string
+
+		expected string
+		ok       bool
+	}{
+		{prefix: "abc", expected: "abd", ok: true},
+		{prefix: "a\U0010FFFF\U0010FFFF", expected: "b", ok: true},
+		{prefix: "a\uD7FF", expected: "a\uE000", ok: true},
+		{prefix: "\uD7FE", expected: "\uD7FF", ok: true},
+		{prefix: "a\uFFFF", expected: "a\U00010000", ok: true},
+		{prefix: "\U0010FFFF", ok: false},
+		{prefix: "", ok: false},
+	}
+
+	for i, fixture := range fixtures {
+		bound, ok := prefixUpperBound(fixture.prefix)
+		assert.Equal(fixture.ok, ok, i)
+		assert.Equal(fixture.expected, bound, i)
+	}
+}
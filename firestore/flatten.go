@@ -0,0 +1,190 @@
+package firestore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// meta columns come first in this order. the document data is prefixed
+// with data. then, like in the json envelope
+var metaColumns = []string{"id", "path", "createTime", "updateTime", "readTime"}
+
+const dataColumn = "data"
+
+// Flatten returns the document as cells keyed by dotted column names, e.g.
+// address.city. maps nested deeper than maxDepth and all arrays are json cells.
+// a negative maxDepth flattens all maps
+func (d *FirestoreDoc) Flatten(maxDepth int) map[string]any {
+	out := make(map[string]any)
+
+	if !d.hasMeta() {
+		flattenInto(out, nil, d.Value, maxDepth)
+		return out
+	}
+
+	for _, column := range metaColumns {
+		out[column] = d.metaValue(column)
+	}
+
+	flattenInto(out, []string{dataColumn}, d.Value, maxDepth)
+
+	return out
+}
+
+// Row returns the cells at columns. columns which weren't flattened, e.g.
+// a selected map, are looked up in the document and become json cells.
+// without the meta envelope the leading id column is the document id
+func (d *FirestoreDoc) Row(columns []string, maxDepth int) []any {
+	flat := d.Flatten(maxDepth)
+
+	row := make([]any, len(columns))
+	n := 0
+	if d.Meta != nil && !d.hasMeta() && len(columns) > 0 {
+		row[0] = d.Meta.ID
+		n = 1
+	}
+
+	for i := n; i < len(columns); i++ {
+		column := columns[i]
+		if v, found := flat[column]; found {
+			row[i] = v
+			continue
+		}
+
+		segments := KeyPath(column).Segments()
+		if d.hasMeta() {
+			if segments[0] != dataColumn || len(segments) == 1 {
+				continue
+			}
+			segments = segments[1:]
+		}
+
+		v, found := lookupField(d.Value, segments)
+		if found {
+			row[i] = cellValue(v)
+		}
+	}
+
+	return row
+}
+
+// FlatColumns returns the union of the flattened columns of docs. meta columns
+// come first, the other columns are sorted by their segments so nested fields
+// stay next to each other
+func FlatColumns(docs []*FirestoreDoc, maxDepth int) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	withMeta := false
+
+	for _, doc := range docs {
+		withMeta = withMeta || doc.hasMeta()
+		for column := range doc.Flatten(maxDepth) {
+			if seen[column] || doc.hasMeta() && slices.Contains(metaColumns, column) {
+				continue
+			}
+
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	slices.SortFunc(columns, func(a, b string) int {
+		return slices.Compare(KeyPath(a).Segments(), KeyPath(b).Segments())
+	})
+
+	return append(leadingColumns(docs, withMeta), columns...)
+}
+
+// SelectColumns returns the columns for selected fields in the order they were selected
+func SelectColumns(docs []*FirestoreDoc, paths []KeyPath, withMeta bool) []string {
+	columns := leadingColumns(docs, withMeta)
+
+	for _, path := range paths {
+		if withMeta {
+			path = NewKeyPath(append([]string{dataColumn}, path.Segments()...)...)
+		}
+		columns = append(columns, string(path))
+	}
+
+	return columns
+}
+
+// leadingColumns returns the meta columns. without the meta envelope the
+// document id still comes first, like in tables, so rows are never empty
+func leadingColumns(docs []*FirestoreDoc, withMeta bool) []string {
+	if withMeta {
+		return slices.Clone(metaColumns)
+	}
+
+	hasID := slices.ContainsFunc(docs, func(doc *FirestoreDoc) bool {
+		return doc.Meta != nil
+	})
+	if hasID {
+		return []string{metaColumns[0]}
+	}
+
+	return nil
+}
+
+func (d *FirestoreDoc) hasMeta() bool {
+	return d.options.WithMeta && d.Meta != nil
+}
+
+func (d *FirestoreDoc) metaValue(column string) any {
+	switch column {
+	case "id":
+		return d.Meta.ID
+	case "path":
+		return d.Meta.Path
+	case "createTime":
+		return d.Meta.CreateTime
+	case "updateTime":
+		return d.Meta.UpdateTime
+	case "readTime":
+		return d.Meta.ReadTime
+	default:
+		return nil
+	}
+}
+
+func flattenInto(out map[string]any, prefix []string, value map[string]any, maxDepth int) {
+	for key, v := range value {
+		segments := append(slices.Clone(prefix), key)
+
+		nested, isMap := v.(map[string]any)
+		if isMap && len(nested) > 0 && maxDepth != 0 {
+			flattenInto(out, segments, nested, maxDepth-1)
+			continue
+		}
+
+		out[string(NewKeyPath(segments...))] = cellValue(v)
+	}
+}
+
+// cellValue converts a field to a readable scalar. timestamps are kept
+// for the writer to format, references become paths and geopoints
+// lat, lng pairs. arrays and maps are encoded as json
+func cellValue(value any) any {
+	switch value := value.(type) {
+	case nil, bool, int64, float64, string, time.Time:
+		return value
+	case []byte:
+		return base64.StdEncoding.EncodeToString(value)
+	case *firestore.DocumentRef:
+		return relativePath(value.Path)
+	case *latlng.LatLng:
+		return fmt.Sprintf("%g, %g", value.GetLatitude(), value.GetLongitude())
+	default:
+		b, err := json.Marshal(plainValue(value))
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(b)
+	}
+}
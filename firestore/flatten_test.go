@@ -0,0 +1,142 @@
+package firestore
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestFlatten(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	value := map[string]any{
+		"name": "Alice",
+		"address": map[string]any{
+			"city": "Berlin",
+			"geo": map[string]any{
+				"point": &latlng.LatLng{Latitude: 52.52, Longitude: 13.405},
+			},
+		},
+		"tags":      []any{"admin", "dev"},
+		"createdAt": ts,
+		"manager":   &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/users/bob"},
+		"x-id":      int64(1),
+		"empty":     map[string]any{},
+	}
+
+	fixtures := []struct {
+		maxDepth int
+
+		expected map[string]any
+	}{
+		{
+			maxDepth: -1,
+			expected: map[string]any{
+				"name":              "Alice",
+				"address.city":      "Berlin",
+				"address.geo.point": "52.52, 13.405",
+				"tags":              `["admin","dev"]`,
+				"createdAt":         ts,
+				"manager":           "users/bob",
+				"`x-id`":            int64(1),
+				"empty":             "{}",
+			},
+		},
+		{
+			maxDepth: 1,
+			expected: map[string]any{
+				"name":         "Alice",
+				"address.city": "Berlin",
+				"address.geo":  `{"point":{"latitude":52.52,"longitude":13.405}}`,
+				"tags":         `["admin","dev"]`,
+				"createdAt":    ts,
+				"manager":      "users/bob",
+				"`x-id`":       int64(1),
+				"empty":        "{}",
+			},
+		},
+		{
+			maxDepth: 0,
+			expected: map[string]any{
+				"name":      "Alice",
+				"address":   `{"city":"Berlin","geo":{"point":{"latitude":52.52,"longitude":13.405}}}`,
+				"tags":      `["admin","dev"]`,
+				"createdAt": ts,
+				"manager":   "users/bob",
+				"`x-id`":    int64(1),
+				"empty":     "{}",
+			},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		doc := NewFirestoreDoc(value)
+		assert.Equal(t, fixture.expected, doc.Flatten(fixture.maxDepth), "depth %d", fixture.maxDepth)
+	}
+}
+
+func TestFlatColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	docs := []*FirestoreDoc{
+		NewFirestoreDoc(map[string]any{"name": "Alice", "address": map[string]any{"zip": "10115", "city": "Berlin"}}),
+		NewFirestoreDoc(map[string]any{"name": "Bob", "age": int64(30), "address_note": "none"}),
+	}
+
+	columns := FlatColumns(docs, -1)
+	assert.Equal([]string{"address.city", "address.zip", "address_note", "age", "name"}, columns)
+
+	assert.Equal([]any{"Berlin", "10115", nil, nil, "Alice"}, docs[0].Row(columns, -1))
+	assert.Equal([]any{nil, nil, "none", int64(30), "Bob"}, docs[1].Row(columns, -1))
+}
+
+func TestFlatColumnsWithMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := NewFirestoreDoc(map[string]any{"address": map[string]any{"city": "Berlin"}})
+	doc.Meta = &DocMeta{ID: "abc", Path: "users/abc", CreateTime: ts, UpdateTime: ts, ReadTime: ts}
+	doc.options.WithMeta = true
+
+	columns := FlatColumns([]*FirestoreDoc{doc}, 0)
+	assert.Equal([]string{"id", "path", "createTime", "updateTime", "readTime", "data.address"}, columns)
+	assert.Equal([]any{"abc", "users/abc", ts, ts, ts, `{"city":"Berlin"}`}, doc.Row(columns, 0))
+}
+
+func TestSelectColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	doc := NewFirestoreDoc(map[string]any{"name": "Alice", "address": map[string]any{"city": "Berlin"}})
+	paths := []KeyPath{"name", "address", "address.city", "missing"}
+
+	columns := SelectColumns([]*FirestoreDoc{doc}, paths, false)
+	assert.Equal([]string{"name", "address", "address.city", "missing"}, columns)
+	assert.Equal([]any{"Alice", `{"city":"Berlin"}`, "Berlin", nil}, doc.Row(columns, -1))
+
+	columns = SelectColumns([]*FirestoreDoc{doc}, []KeyPath{"name", "`x-id`"}, true)
+	assert.Equal([]string{"id", "path", "createTime", "updateTime", "readTime", "data.name", "data.`x-id`"}, columns)
+}
+
+func TestColumnsWithID(t *testing.T) {
+	assert := assert.New(t)
+
+	doc := NewFirestoreDoc(map[string]any{"id": "field", "name": "Alice"})
+	doc.Meta = &DocMeta{ID: "abc", Path: "users/abc"}
+
+	columns := FlatColumns([]*FirestoreDoc{doc}, -1)
+	assert.Equal([]string{"id", "id", "name"}, columns)
+	assert.Equal([]any{"abc", "field", "Alice"}, doc.Row(columns, -1))
+
+	// --select '' selects no fields at all
+	keysOnly := NewFirestoreDoc(map[string]any{})
+	keysOnly.Meta = &DocMeta{ID: "def", Path: "users/def"}
+
+	columns = SelectColumns([]*FirestoreDoc{keysOnly}, []KeyPath{}, false)
+	assert.Equal([]string{"id"}, columns)
+	assert.Equal([]any{"def"}, keysOnly.Row(columns, -1))
+
+	columns = FlatColumns([]*FirestoreDoc{keysOnly}, -1)
+	assert.Equal([]string{"id"}, columns)
+}
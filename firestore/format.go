@@ -0,0 +1,35 @@
+package firestore
+
+import "fmt"
+
+type Format int
+
+const (
+	// FormatJSON encodes documents as plain json. some firestore types
+	// (e.g. timestamps and references) can't be told apart from strings
+	FormatJSON Format = iota + 1
+	// FormatTyped encodes every value together with its firestore type
+	FormatTyped
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch format {
+	case "", "json":
+		return FormatJSON, nil
+	case "typed":
+		return FormatTyped, nil
+	default:
+		return Format(0), fmt.Errorf("unknown format %s", format)
+	}
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatTyped:
+		return "typed"
+	default:
+		return ""
+	}
+}
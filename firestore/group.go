@@ -0,0 +1,58 @@
+package firestore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrInvalidCollectionID = errors.New("invalid collection id")
+)
+
+// ValidateCollectionGroup validates a collection group id and the
+// optional parent document it is scoped to
+func ValidateCollectionGroup(collectionID, parent string) error {
+	if collectionID == "" || strings.Contains(collectionID, "/") {
+		return ErrInvalidCollectionID
+	}
+	if parent != "" && !IsDocumentPath(parent) {
+		return fmt.Errorf("parent %s must be a document path", parent)
+	}
+
+	return nil
+}
+
+// collectionGroupQuery returns a query over all collections with the id
+// collectionID. if parent is set only collections below that document are included
+func collectionGroupQuery(client *firestore.Client, collectionID, parent string) (firestore.Query, error) {
+	query := client.CollectionGroup(collectionID).Query
+	if parent == "" {
+		return query, nil
+	}
+
+	// the sdk always uses the database root as parent of collection groups.
+	// the parent can only be changed on the serialized query
+	b, err := query.Serialize()
+	if err != nil {
+		return query, fmt.Errorf("serializing query: %v", err)
+	}
+
+	var req firestorepb.RunQueryRequest
+	err = proto.Unmarshal(b, &req)
+	if err != nil {
+		return query, fmt.Errorf("unmarshalling query: %v", err)
+	}
+	req.Parent = client.Doc(parent).Path
+
+	b, err = proto.Marshal(&req)
+	if err != nil {
+		return query, fmt.Errorf("marshalling query: %v", err)
+	}
+
+	return query.Deserialize(b)
+}
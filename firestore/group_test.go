@@ -0,0 +1,58 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidateCollectionGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(ValidateCollectionGroup("orders", ""))
+	assert.NoError(ValidateCollectionGroup("orders", "users/abc"))
+
+	assert.ErrorIs(ValidateCollectionGroup("", ""), ErrInvalidCollectionID)
+	assert.ErrorIs(ValidateCollectionGroup("users/abc/orders", ""), ErrInvalidCollectionID)
+	assert.Error(ValidateCollectionGroup("orders", "users"))
+}
+
+func TestCollectionGroupQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	fixtures := []struct {
+		parent string
+
+		expected string
+	}{
+		{parent: "", expected: "projects/demo-test/databases/(default)/documents"},
+		{parent: "users/abc", expected: "projects/demo-test/databases/(default)/documents/users/abc"},
+	}
+
+	for _, fixture := range fixtures {
+		q, err := collectionGroupQuery(client, "orders", fixture.parent)
+		assert.NoError(err)
+
+		b, err := q.Where("total", ">", 10).Serialize()
+		assert.NoError(err)
+
+		var req firestorepb.RunQueryRequest
+		assert.NoError(proto.Unmarshal(b, &req))
+
+		assert.Equal(fixture.expected, req.GetParent())
+		from := req.GetStructuredQuery().GetFrom()
+		if assert.Len(from, 1) {
+			assert.Equal("orders", from[0].GetCollectionId())
+			assert.True(from[0].GetAllDescendants())
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package firestore
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// firestore has no GROUP BY. documents are grouped while they are streamed
+
+type (
+	Bucket int
+
+	// GroupKey groups documents by the value of a field. timestamps can be
+	// truncated to a bucket to group e.g. by day
+	GroupKey struct {
+		Field  KeyPath
+		Bucket Bucket
+	}
+
+	GroupOptions struct {
+		Keys []GroupKey
+		Sums []KeyPath
+		Avgs []KeyPath
+	}
+
+	// GroupResult holds one row per group. each row has a value for every column
+	GroupResult struct {
+		Columns []string
+		Rows    [][]any
+	}
+
+	grouper struct {
+		options GroupOptions
+		groups  map[string]*group
+	}
+
+	group struct {
+		values []any
+		count  int64
+		sums   []numberSum
+		avgs   []numberSum
+	}
+
+	// numberSum sums up integers as int64 until the first double is added
+	numberSum struct {
+		ints    int64
+		doubles float64
+		isFloat bool
+		count   int64
+	}
+)
+
+const (
+	BucketNone Bucket = iota
+	BucketHour
+	BucketDay
+	BucketWeek
+	BucketMonth
+	BucketYear
+)
+
+var (
+	ErrInvalidBucket = errors.New("invalid bucket. use one of hour, day, week, month, year")
+)
+
+func ParseBucket(bucket string) (Bucket, error) {
+	switch strings.ToLower(bucket) {
+	case "hour":
+		return BucketHour, nil
+	case "day":
+		return BucketDay, nil
+	case "week":
+		return BucketWeek, nil
+	case "month":
+		return BucketMonth, nil
+	case "year":
+		return BucketYear, nil
+	default:
+		return BucketNone, ErrInvalidBucket
+	}
+}
+
+func (b Bucket) String() string {
+	switch b {
+	case BucketHour:
+		return "hour"
+	case BucketDay:
+		return "day"
+	case BucketWeek:
+		return "week"
+	case BucketMonth:
+		return "month"
+	case BucketYear:
+		return "year"
+	default:
+		return ""
+	}
+}
+
+// label formats t as the start of its bucket in utc. weeks start on monday
+func (b Bucket) label(t time.Time) string {
+	t = t.UTC()
+
+	switch b {
+	case BucketHour:
+		return t.Format("2006-01-02T15:00")
+	case BucketDay:
+		return t.Format(time.DateOnly)
+	case BucketWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset).Format(time.DateOnly)
+	case BucketMonth:
+		return t.Format("2006-01")
+	case BucketYear:
+		return t.Format("2006")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+func (k GroupKey) String() string {
+	if k.Bucket == BucketNone {
+		return string(k.Field)
+	}
+
+	return fmt.Sprintf("%s:%s", k.Field, k.Bucket)
+}
+
+// value returns the group value of a document. documents without
+// a timestamp at a bucket field are grouped as null
+func (k GroupKey) value(data map[string]any) any {
+	v, found := lookupField(data, k.Field.Segments())
+	if !found {
+		return nil
+	}
+
+	if k.Bucket == BucketNone {
+		return plainValue(v)
+	}
+
+	t, ok := v.(time.Time)
+	if !ok {
+		return nil
+	}
+	return k.Bucket.label(t)
+}
+
+func (o GroupOptions) columns() []string {
+	columns := make([]string, 0, len(o.Keys)+1+len(o.Sums)+len(o.Avgs))
+	for _, key := range o.Keys {
+		columns = append(columns, string(key.Field))
+	}
+
+	columns = append(columns, Aggregation{Kind: AggregateCount}.Alias())
+	for _, field := range o.Sums {
+		columns = append(columns, Aggregation{Kind: AggregateSum, Field: field}.Alias())
+	}
+	for _, field := range o.Avgs {
+		columns = append(columns, Aggregation{Kind: AggregateAvg, Field: field}.Alias())
+	}
+
+	return columns
+}
+
+// fields returns the fields read to group and sum up documents
+func (o GroupOptions) fields() []KeyPath {
+	fields := make([]KeyPath, 0, len(o.Keys)+len(o.Sums)+len(o.Avgs))
+	for _, key := range o.Keys {
+		fields = append(fields, key.Field)
+	}
+	fields = append(fields, o.Sums...)
+	return append(fields, o.Avgs...)
+}
+
+// GroupBy streams all documents of the query and counts them per group.
+// groups are sorted by their key values
+func (b QueryClient) GroupBy(options GroupOptions) (GroupResult, error) {
+	b.selectAlso(options.fields())
+	g := newGrouper(options)
+
+	_, err := b.streamSnapshots(func(snapshot *firestore.DocumentSnapshot) error {
+		return g.add(snapshot.Data())
+	})
+	if err != nil {
+		return GroupResult{}, err
+	}
+
+	return g.result(), nil
+}
+
+func newGrouper(options GroupOptions) *grouper {
+	return &grouper{
+		options: options,
+		groups:  make(map[string]*group),
+	}
+}
+
+func (g *grouper) add(data map[string]any) error {
+	values := make([]any, len(g.options.Keys))
+	for i, key := range g.options.Keys {
+		values[i] = key.value(data)
+	}
+
+	id, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("encoding group key: %v", err)
+	}
+
+	grp, found := g.groups[string(id)]
+	if !found {
+		grp = &group{
+			values: values,
+			sums:   make([]numberSum, len(g.options.Sums)),
+			avgs:   make([]numberSum, len(g.options.Avgs)),
+		}
+		g.groups[string(id)] = grp
+	}
+
+	grp.count++
+	for i, field := range g.options.Sums {
+		grp.sums[i].add(data, field)
+	}
+	for i, field := range g.options.Avgs {
+		grp.avgs[i].add(data, field)
+	}
+
+	return nil
+}
+
+func (g *grouper) result() GroupResult {
+	sorted := make([]*group, 0, len(g.groups))
+	for _, grp := range g.groups {
+		sorted = append(sorted, grp)
+	}
+	slices.SortFunc(sorted, func(a, b *group) int {
+		return compareGroupValues(a.values, b.values)
+	})
+
+	result := GroupResult{
+		Columns: g.options.columns(),
+		Rows:    make([][]any, 0, len(sorted)),
+	}
+	for _, grp := range sorted {
+		row := slices.Clone(grp.values)
+		row = append(row, grp.count)
+		for _, s := range grp.sums {
+			row = append(row, s.sum())
+		}
+		for _, s := range grp.avgs {
+			row = append(row, s.avg())
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result
+}
+
+// Objects returns the rows as objects keyed by column
+func (r GroupResult) Objects() []map[string]any {
+	out := make([]map[string]any, len(r.Rows))
+	for i, row := range r.Rows {
+		obj := make(map[string]any, len(r.Columns))
+		for j, column := range r.Columns {
+			obj[column] = row[j]
+		}
+		out[i] = obj
+	}
+
+	return out
+}
+
+// add adds the number at field. other types are ignored like in firestore aggregations
+func (s *numberSum) add(data map[string]any, field KeyPath) {
+	v, found := lookupField(data, field.Segments())
+	if !found {
+		return
+	}
+
+	switch v := v.(type) {
+	case int64:
+		s.ints += v
+	case float64:
+		s.doubles += v
+		s.isFloat = true
+	default:
+		return
+	}
+	s.count++
+}
+
+func (s numberSum) sum() any {
+	if s.isFloat {
+		return plainValue(s.doubles + float64(s.ints))
+	}
+	return s.ints
+}
+
+func (s numberSum) avg() any {
+	if s.count == 0 {
+		return nil
+	}
+	return plainValue((s.doubles + float64(s.ints)) / float64(s.count))
+}
+
+func compareGroupValues(a, b []any) int {
+	for i := range a {
+		if c := compareGroupValue(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareGroupValue orders null before booleans, numbers, strings and all other values
+func compareGroupValue(a, b any) int {
+	rankA, rankB := groupValueRank(a), groupValueRank(b)
+	if rankA != rankB {
+		return cmp.Compare(rankA, rankB)
+	}
+
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		return cmp.Compare(boolRank(a), boolRank(b.(bool)))
+	case int64, float64:
+		return cmp.Compare(toFloat(a), toFloat(b))
+	case string:
+		return cmp.Compare(a, b.(string))
+	default:
+		ja, _ := json.Marshal(a)
+		jb, _ := json.Marshal(b)
+		return cmp.Compare(string(ja), string(jb))
+	}
+}
+
+func groupValueRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case string:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,85 @@
+package firestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupBy(t *testing.T) {
+	assert := assert.New(t)
+
+	docs := []map[string]any{
+		{"status": "open", "total": int64(10), "createdAt": time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{"status": "done", "total": 2.5, "createdAt": time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)},
+		{"status": "open", "total": int64(5), "createdAt": time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{"status": "open", "total": "n/a", "createdAt": time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"total": int64(1)},
+	}
+
+	g := newGrouper(GroupOptions{
+		Keys: []GroupKey{{Field: "status"}},
+		Sums: []KeyPath{"total"},
+		Avgs: []KeyPath{"total"},
+	})
+	for _, doc := range docs {
+		assert.NoError(g.add(doc))
+	}
+
+	result := g.result()
+	assert.Equal([]string{"status", "count", "sum_total", "avg_total"}, result.Columns)
+	assert.Equal([][]any{
+		{nil, int64(1), int64(1), 1.0},
+		{"done", int64(1), 2.5, 2.5},
+		{"open", int64(3), int64(15), 7.5},
+	}, result.Rows)
+
+	assert.Equal([]map[string]any{
+		{"status": nil, "count": int64(1), "sum_total": int64(1), "avg_total": 1.0},
+		{"status": "done", "count": int64(1), "sum_total": 2.5, "avg_total": 2.5},
+		{"status": "open", "count": int64(3), "sum_total": int64(15), "avg_total": 7.5},
+	}, result.Objects())
+
+	g = newGrouper(GroupOptions{
+		Keys: []GroupKey{{Field: "createdAt", Bucket: BucketDay}, {Field: "status"}},
+	})
+	for _, doc := range docs {
+		assert.NoError(g.add(doc))
+	}
+
+	assert.Equal([][]any{
+		{nil, nil, int64(1)},
+		{"2025-01-01", "done", int64(1)},
+		{"2025-01-01", "open", int64(1)},
+		{"2025-01-02", "open", int64(2)},
+	}, g.result().Rows)
+}
+
+func TestBucketLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	// a wednesday
+	ts := time.Date(2025, 1, 15, 13, 45, 0, 0, time.FixedZone("CET", 3600))
+
+	assert.Equal("2025-01-15T12:00", BucketHour.label(ts))
+	assert.Equal("2025-01-15", BucketDay.label(ts))
+	assert.Equal("2025-01-13", BucketWeek.label(ts))
+	assert.Equal("2025-01", BucketMonth.label(ts))
+	assert.Equal("2025", BucketYear.label(ts))
+
+	// sundays belong to the week starting on the monday before
+	assert.Equal("2025-01-13", BucketWeek.label(time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestCompareGroupValue(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(-1, compareGroupValue(nil, false))
+	assert.Equal(-1, compareGroupValue(false, true))
+	assert.Equal(-1, compareGroupValue(true, int64(0)))
+	assert.Equal(-1, compareGroupValue(int64(9), 10.5))
+	assert.Equal(1, compareGroupValue(int64(10), int64(9)))
+	assert.Equal(-1, compareGroupValue(int64(10), "a"))
+	assert.Equal(0, compareGroupValue("a", "a"))
+}
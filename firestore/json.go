@@ -1,8 +1,11 @@
 package firestore
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 )
 
 type (
@@ -11,30 +14,146 @@ type (
 	}
 	JSONArray struct {
 		Values []map[string]any
+		// IDs holds the document id of each value that was read from a
+		// --with-meta envelope. it is empty without DecodeOptions.Envelopes
+		IDs []string
+	}
+
+	DecodeOptions struct {
+		Format Format
+		// NumberTypes forces the numbers at the given field paths to
+		// a specific type instead of deriving it from the input
+		NumberTypes map[KeyPath]NumberType
+		// Envelopes reads documents wrapped in the envelope of
+		// DocOptions.WithMeta instead of plain objects
+		Envelopes bool
 	}
 )
 
+func DecodeJSONObject(r io.Reader, options DecodeOptions) (JSONObject, error) {
+	var j JSONObject
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return j, err
+	}
+
+	return j, j.applyOptions(options)
+}
+
+func (j *JSONObject) applyOptions(options DecodeOptions) error {
+	if options.Envelopes {
+		data, _, err := unwrapEnvelope(j.Value)
+		if err != nil {
+			return err
+		}
+
+		j.Value = data
+	}
+
+	if options.Format == FormatTyped {
+		value, err := fromTypedFields(j.Value)
+		if err != nil {
+			return fmt.Errorf("decoding typed value: %v", err)
+		}
+
+		j.Value = value
+	}
+
+	if err := checkArrayTransforms(j.Value); err != nil {
+		return err
+	}
+
+	return forceNumberTypes(j.Value, options.NumberTypes)
+}
+
+func DecodeJSONArray(r io.Reader, options DecodeOptions) (JSONArray, error) {
+	var j JSONArray
+	if err := json.NewDecoder(r).Decode(&j); err != nil {
+		return j, err
+	}
+
+	return j, j.applyOptions(options)
+}
+
+func (j *JSONArray) applyOptions(options DecodeOptions) error {
+	if options.Envelopes {
+		j.IDs = make([]string, len(j.Values))
+		for i, obj := range j.Values {
+			data, id, err := unwrapEnvelope(obj)
+			if err != nil {
+				return fmt.Errorf("pos %d: %v", i+1, err)
+			}
+
+			j.Values[i] = data
+			j.IDs[i] = id
+		}
+	}
+
+	if options.Format == FormatTyped {
+		for i, obj := range j.Values {
+			value, err := fromTypedFields(obj)
+			if err != nil {
+				return fmt.Errorf("decoding typed value at pos %d: %v", i+1, err)
+			}
+
+			j.Values[i] = value
+		}
+	}
+
+	for i, obj := range j.Values {
+		if err := checkArrayTransforms(obj); err != nil {
+			return fmt.Errorf("pos %d: %v", i+1, err)
+		}
+		if err := forceNumberTypes(obj, options.NumberTypes); err != nil {
+			return fmt.Errorf("pos %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func unmarshalPreservingNumbers(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return normalizeNumbers(v)
+}
+
 func (j *JSONObject) UnmarshalJSON(bytes []byte) error {
-	var data any
-	if err := json.Unmarshal(bytes, &data); err != nil {
+	data, err := unmarshalPreservingNumbers(bytes)
+	if err != nil {
 		return err
 	}
 
+	return j.setData(data, "json")
+}
+
+// setData sets the value from a decoded object
+func (j *JSONObject) setData(data any, encoding string) error {
 	switch data := data.(type) {
 	case map[string]any:
 		j.Value = data
 		return nil
 	default:
-		return fmt.Errorf("expected json object, got %T", data)
+		return fmt.Errorf("expected %s object, got %T", encoding, data)
 	}
 }
 
 func (j *JSONArray) UnmarshalJSON(bytes []byte) error {
-	var data any
-	if err := json.Unmarshal(bytes, &data); err != nil {
+	data, err := unmarshalPreservingNumbers(bytes)
+	if err != nil {
 		return err
 	}
 
+	return j.setData(data, "json")
+}
+
+// setData sets the values from a decoded array of objects
+func (j *JSONArray) setData(data any, encoding string) error {
 	switch data := data.(type) {
 	case []any:
 		objects := make([]map[string]any, len(data))
@@ -43,7 +162,7 @@ func (j *JSONArray) UnmarshalJSON(bytes []byte) error {
 			case map[string]any:
 				objects[i] = value
 			default:
-				return fmt.Errorf("no json object in array at pos %d", i+1)
+				return fmt.Errorf("no %s object in array at pos %d", encoding, i+1)
 			}
 		}
 
@@ -51,6 +170,37 @@ func (j *JSONArray) UnmarshalJSON(bytes []byte) error {
 
 		return nil
 	default:
-		return fmt.Errorf("expected json array, got %T", data)
+		return fmt.Errorf("expected %s array, got %T", encoding, data)
 	}
 }
+
+// envelopeKeys are the keys of the envelope written by DocOptions.WithMeta
+var envelopeKeys = []string{"id", "path", "createTime", "updateTime", "readTime", "data"}
+
+// unwrapEnvelope returns the document data and id of an object that was
+// produced by querying with DocOptions.WithMeta. the object must have exactly
+// the keys of the envelope
+func unwrapEnvelope(obj map[string]any) (map[string]any, string, error) {
+	for _, key := range envelopeKeys {
+		if _, found := obj[key]; !found {
+			return nil, "", fmt.Errorf("envelope is missing %s", key)
+		}
+	}
+	if len(obj) != len(envelopeKeys) {
+		return nil, "", fmt.Errorf("envelope only has the keys %s", strings.Join(envelopeKeys, ", "))
+	}
+
+	data, ok := obj["data"].(map[string]any)
+	if !ok {
+		return nil, "", fmt.Errorf("envelope data must be an object, got %T", obj["data"])
+	}
+	id, ok := obj["id"].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("envelope id must be a string, got %T", obj["id"])
+	}
+	if _, ok := obj["path"].(string); !ok {
+		return nil, "", fmt.Errorf("envelope path must be a string, got %T", obj["path"])
+	}
+
+	return data, id, nil
+}
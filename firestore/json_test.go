@@ -2,6 +2,7 @@ package firestore
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -73,3 +74,88 @@ func TestJSONArrayDecoding(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONEnvelopeDecoding(t *testing.T) {
+	assert := assert.New(t)
+
+	envelope := `{"id": "abc", "path": "users/abc", "createTime": "2025-01-01T00:00:00Z", "updateTime": "2025-01-01T00:00:00Z", "readTime": "2025-01-02T00:00:00Z", "data": {"foo": "bar"}}`
+	options := DecodeOptions{Envelopes: true}
+
+	obj, err := DecodeJSONObject(strings.NewReader(envelope), options)
+	assert.NoError(err)
+	assert.Equal(map[string]any{"foo": "bar"}, obj.Value)
+
+	arr, err := DecodeJSONArray(strings.NewReader("["+envelope+"]"), options)
+	assert.NoError(err)
+	assert.Equal([]map[string]any{{"foo": "bar"}}, arr.Values)
+	assert.Equal([]string{"abc"}, arr.IDs)
+
+	// plain documents with envelope keys are kept as they are
+	plain := `{"id": "a1", "path": "x/y", "data": {"foo": "bar"}}`
+	obj, err = DecodeJSONObject(strings.NewReader(plain), DecodeOptions{})
+	assert.NoError(err)
+	assert.Equal(map[string]any{"id": "a1", "path": "x/y", "data": map[string]any{"foo": "bar"}}, obj.Value)
+
+	arr, err = DecodeJSONArray(strings.NewReader("["+envelope+"]"), DecodeOptions{})
+	assert.NoError(err)
+	assert.Equal("abc", arr.Values[0]["id"])
+	assert.Empty(arr.IDs)
+
+	fixtures := []string{
+		plain,
+		`{"id": "abc", "path": "users/abc", "createTime": "", "updateTime": "", "readTime": "", "data": {}, "foo": "bar"}`,
+		`{"id": "abc", "path": "users/abc", "createTime": "", "updateTime": "", "readTime": "", "data": "bar"}`,
+		`{"id": 1, "path": "users/abc", "createTime": "", "updateTime": "", "readTime": "", "data": {}}`,
+	}
+	for i, fixture := range fixtures {
+		_, err := DecodeJSONObject(strings.NewReader(fixture), options)
+		assert.Error(err, i)
+	}
+}
+
+func TestJSONNumberDecoding(t *testing.T) {
+	assert := assert.New(t)
+
+	var obj JSONObject
+	err := json.Unmarshal([]byte(`{"int": 42, "double": 4.2, "exp": 1e3, "big": 92233720368547758070, "list": [1, 1.5], "map": {"int": -1}}`), &obj)
+	assert.NoError(err)
+	assert.Equal(map[string]any{
+		"int":    int64(42),
+		"double": 4.2,
+		"exp":    float64(1000),
+		"big":    92233720368547758070.0,
+		"list":   []any{int64(1), 1.5},
+		"map":    map[string]any{"int": int64(-1)},
+	}, obj.Value)
+}
+
+func TestJSONForcedNumberTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	options := DecodeOptions{
+		NumberTypes: map[KeyPath]NumberType{
+			"price":       NumberDouble,
+			"stock.count": NumberInt,
+			"scores":      NumberDouble,
+			"missing":     NumberInt,
+		},
+	}
+
+	obj, err := DecodeJSONObject(strings.NewReader(`{"price": 10, "stock": {"count": 5.0}, "scores": [1, 2]}`), options)
+	assert.NoError(err)
+	assert.Equal(map[string]any{
+		"price":  float64(10),
+		"stock":  map[string]any{"count": int64(5)},
+		"scores": []any{float64(1), float64(2)},
+	}, obj.Value)
+
+	_, err = DecodeJSONObject(strings.NewReader(`{"stock": {"count": 5.5}}`), options)
+	assert.Error(err)
+
+	_, err = DecodeJSONObject(strings.NewReader(`{"price": "10"}`), options)
+	assert.Error(err)
+
+	arr, err := DecodeJSONArray(strings.NewReader(`[{"price": 1}, {"price": 2.5}]`), options)
+	assert.NoError(err)
+	assert.Equal([]map[string]any{{"price": float64(1)}, {"price": 2.5}}, arr.Values)
+}
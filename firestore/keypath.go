@@ -0,0 +1,123 @@
+package firestore
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// KeyPath is a field path in firestore notation. segments which are not
+// simple identifiers are quoted with backticks, e.g. meta.`x-id`.
+// see https://firebase.google.com/docs/firestore/quotas#limits
+type KeyPath string
+
+var (
+	ErrEmptyKeyPath = errors.New("empty field path")
+
+	simpleSegmentRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+)
+
+// NewKeyPath joins segments to a KeyPath and quotes them where needed
+func NewKeyPath(segments ...string) KeyPath {
+	quoted := make([]string, len(segments))
+	for i, segment := range segments {
+		quoted[i] = quoteSegment(segment)
+	}
+
+	return KeyPath(strings.Join(quoted, "."))
+}
+
+// ParseKeyPath validates path and returns it in canonical notation
+func ParseKeyPath(path string) (KeyPath, error) {
+	segments, err := splitKeyPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	return NewKeyPath(segments...), nil
+}
+
+func (p KeyPath) Segments() []string {
+	segments, err := splitKeyPath(string(p))
+	if err != nil {
+		return strings.Split(string(p), ".")
+	}
+
+	return segments
+}
+
+func (p KeyPath) FieldPath() firestore.FieldPath {
+	return firestore.FieldPath(p.Segments())
+}
+
+func quoteSegment(segment string) string {
+	if simpleSegmentRe.MatchString(segment) {
+		return segment
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(segment)
+	return "`" + escaped + "`"
+}
+
+func splitKeyPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, ErrEmptyKeyPath
+	}
+
+	var (
+		segments []string
+		runes    = []rune(path)
+	)
+	for i := 0; i < len(runes); i++ {
+		var segment strings.Builder
+
+		if runes[i] == '`' {
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					segment.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == '`' {
+					closed = true
+					i++
+					break
+				}
+				segment.WriteRune(runes[i])
+			}
+
+			if !closed {
+				return nil, fmt.Errorf("unterminated backtick in field path %s", path)
+			}
+			if segment.Len() == 0 {
+				return nil, fmt.Errorf("empty segment in field path %s", path)
+			}
+		} else {
+			for ; i < len(runes) && runes[i] != '.'; i++ {
+				segment.WriteRune(runes[i])
+			}
+
+			if segment.Len() == 0 {
+				return nil, fmt.Errorf("empty segment in field path %s", path)
+			}
+			if !simpleSegmentRe.MatchString(segment.String()) {
+				return nil, fmt.Errorf("invalid segment %s in field path %s. quote it with backticks: `%s`", segment.String(), path, segment.String())
+			}
+		}
+
+		segments = append(segments, segment.String())
+
+		if i < len(runes) && runes[i] != '.' {
+			return nil, fmt.Errorf("expected '.' after quoted segment in field path %s", path)
+		}
+		if i == len(runes)-1 {
+			return nil, fmt.Errorf("empty segment in field path %s", path)
+		}
+	}
+
+	return segments, nil
+}
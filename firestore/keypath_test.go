@@ -0,0 +1,62 @@
+package firestore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyPath(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		path      string
+		segments  []string
+		canonical KeyPath
+	}{
+		{path: "foo", segments: []string{"foo"}, canonical: "foo"},
+		{path: "address2", segments: []string{"address2"}, canonical: "address2"},
+		{path: "a.b_c", segments: []string{"a", "b_c"}, canonical: "a.b_c"},
+		{path: "__name__", segments: []string{"__name__"}, canonical: "__name__"},
+		{path: "meta.`x-id`", segments: []string{"meta", "x-id"}, canonical: "meta.`x-id`"},
+		{path: "items.`0`.sku", segments: []string{"items", "0", "sku"}, canonical: "items.`0`.sku"},
+		{path: "`meta`.`x-id`", segments: []string{"meta", "x-id"}, canonical: "meta.`x-id`"},
+		{path: "`a.b`", segments: []string{"a.b"}, canonical: "`a.b`"},
+		{path: "`$price`", segments: []string{"$price"}, canonical: "`$price`"},
+		{path: "`größe`", segments: []string{"größe"}, canonical: "`größe`"},
+		{path: "`back\\`tick`", segments: []string{"back`tick"}, canonical: "`back\\`tick`"},
+		{path: "`back\\\\slash`", segments: []string{"back\\slash"}, canonical: "`back\\\\slash`"},
+	}
+
+	for _, fixture := range fixtures {
+		key, err := ParseKeyPath(fixture.path)
+
+		assert.NoError(err, fixture.path)
+		assert.Equal(fixture.canonical, key, fixture.path)
+		assert.Equal(fixture.segments, key.Segments(), fixture.path)
+	}
+}
+
+func TestParseKeyPathErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []string{
+		"",
+		".",
+		"a.",
+		".a",
+		"a..b",
+		"0",
+		"items.0.sku",
+		"meta.x-id",
+		"größe",
+		"`unterminated",
+		"``",
+		"`a`b",
+	}
+
+	for _, fixture := range fixtures {
+		_, err := ParseKeyPath(fixture)
+		assert.Error(err, fixture)
+	}
+}
@@ -0,0 +1,128 @@
+package firestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+type NumberType int
+
+const (
+	NumberInt NumberType = iota + 1
+	NumberDouble
+)
+
+// normalizeNumbers replaces the json.Number values produced by a decoder
+// with UseNumber enabled. whole numbers become int64, everything else float64
+func normalizeNumbers(value any) (any, error) {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, nil
+		}
+
+		f, err := value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s: %v", value, err)
+		}
+		return f, nil
+	case []any:
+		for i, v := range value {
+			normalized, err := normalizeNumbers(v)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = normalized
+		}
+		return value, nil
+	case map[string]any:
+		for key, v := range value {
+			normalized, err := normalizeNumbers(v)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = normalized
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// forceNumberTypes converts the numbers at the given field paths of obj.
+// paths that don't exist in obj are skipped
+func forceNumberTypes(obj map[string]any, types map[KeyPath]NumberType) error {
+	for path, numberType := range types {
+		if err := forceNumberType(obj, path.Segments(), numberType); err != nil {
+			return fmt.Errorf("field %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+func forceNumberType(obj map[string]any, segments []string, numberType NumberType) error {
+	value, found := obj[segments[0]]
+	if !found {
+		return nil
+	}
+
+	if len(segments) > 1 {
+		nested, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		return forceNumberType(nested, segments[1:], numberType)
+	}
+
+	if values, ok := value.([]any); ok {
+		for i, v := range values {
+			converted, err := convertNumber(v, numberType)
+			if err != nil {
+				return fmt.Errorf("array index %d: %v", i, err)
+			}
+			values[i] = converted
+		}
+		return nil
+	}
+
+	if sentinel, ok := value.(map[string]any); ok {
+		if increment, found := sentinel[transformIncrement]; found && len(sentinel) == 1 {
+			converted, err := convertNumber(increment, numberType)
+			if err != nil {
+				return err
+			}
+			sentinel[transformIncrement] = converted
+			return nil
+		}
+	}
+
+	converted, err := convertNumber(value, numberType)
+	if err != nil {
+		return err
+	}
+	obj[segments[0]] = converted
+
+	return nil
+}
+
+func convertNumber(value any, numberType NumberType) (any, error) {
+	switch value := value.(type) {
+	case int64:
+		if numberType == NumberDouble {
+			return float64(value), nil
+		}
+		return value, nil
+	case float64:
+		if numberType == NumberInt {
+			if value != math.Trunc(value) || math.IsInf(value, 0) {
+				return nil, fmt.Errorf("%v is not a whole number", value)
+			}
+			return int64(value), nil
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("expected number, got %T", value)
+	}
+}
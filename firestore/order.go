@@ -1,6 +1,17 @@
 package firestore
 
-import "cloud.google.com/go/firestore"
+import (
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/firestore"
+)
+
+// OrderBy orders query results by a field
+type OrderBy struct {
+	Path      KeyPath
+	Direction firestore.Direction
+}
 
 func GetFirestoreDirection(desc bool) firestore.Direction {
 	if desc {
@@ -8,3 +19,57 @@ func GetFirestoreDirection(desc bool) firestore.Direction {
 	}
 	return firestore.Asc
 }
+
+func (o OrderBy) String() string {
+	if o.Direction == firestore.Desc {
+		return fmt.Sprintf("%s desc", o.Path)
+	}
+	return fmt.Sprintf("%s asc", o.Path)
+}
+
+// ValidateOrderBy checks that the first order by field is a field with an
+// inequality filter, as firestore requires inequality fields to be ordered first
+func ValidateOrderBy(filters []Filter, orderBy []OrderBy) error {
+	if len(orderBy) == 0 {
+		return nil
+	}
+
+	fields := inequalityFields(filters)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	for _, field := range fields {
+		if orderBy[0].Path == field {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the first order by field must be the inequality filter field %s, got %s", fields[0], orderBy[0].Path)
+}
+
+// inequalityFields returns the fields filtered by <, <=, >, >=, != or not-in
+func inequalityFields(filters []Filter) []KeyPath {
+	var fields []KeyPath
+	for _, filter := range filters {
+		var found []KeyPath
+		switch filter := filter.(type) {
+		case Where:
+			if filter.Operator.isInequality() {
+				found = []KeyPath{filter.Key}
+			}
+		case AndFilter:
+			found = inequalityFields(filter.Filters)
+		case OrFilter:
+			found = inequalityFields(filter.Filters)
+		}
+
+		for _, field := range found {
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+
+	return fields
+}
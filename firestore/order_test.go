@@ -0,0 +1,42 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOrderBy(t *testing.T) {
+	assert := assert.New(t)
+
+	age := Where{Key: "age", Operator: Gte, Value: NewIntValue(18)}
+	status := Where{Key: "status", Operator: Eq, Value: NewStringValue("open")}
+	priority := Where{Key: "priority", Operator: NotIn, Value: NewArrayValue()}
+
+	fixtures := []struct {
+		filters []Filter
+		orderBy []OrderBy
+
+		valid bool
+	}{
+		{filters: nil, orderBy: []OrderBy{{Path: "age"}}, valid: true},
+		{filters: []Filter{status}, orderBy: []OrderBy{{Path: "createdAt"}}, valid: true},
+		{filters: []Filter{age}, orderBy: nil, valid: true},
+		{filters: []Filter{age}, orderBy: []OrderBy{{Path: "age", Direction: firestore.Desc}, {Path: "name"}}, valid: true},
+		{filters: []Filter{age}, orderBy: []OrderBy{{Path: "name"}, {Path: "age"}}, valid: false},
+		{filters: []Filter{AndFilter{Filters: []Filter{status, age}}}, orderBy: []OrderBy{{Path: "name"}}, valid: false},
+		{filters: []Filter{OrFilter{Filters: []Filter{status, priority}}}, orderBy: []OrderBy{{Path: "priority"}}, valid: true},
+		{filters: []Filter{IDPrefixFilter("abc")}, orderBy: []OrderBy{{Path: DocumentID}}, valid: true},
+		{filters: []Filter{IDPrefixFilter("abc")}, orderBy: []OrderBy{{Path: "age"}}, valid: false},
+	}
+
+	for i, fixture := range fixtures {
+		err := ValidateOrderBy(fixture.filters, fixture.orderBy)
+		if fixture.valid {
+			assert.NoError(err, i)
+		} else {
+			assert.Error(err, i)
+		}
+	}
+}
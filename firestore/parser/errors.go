@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Error is returned for invalid input and points at the offending
+// position of the source. use errors.As to access the details
+type Error struct {
+	Source string
+	// Offset is the byte offset of the error in Source
+	Offset  int
+	Message string
+	// Hint suggests a fix for common mistakes. empty if there is none
+	Hint string
+}
+
+// Error prints the message followed by the source with a caret
+// under the offending position, e.g.
+//
+//	expected operator, got assign = at offset 7
+//	  status = "open"
+//	         ^
+//	hint: use == to compare values
+func (e *Error) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s at offset %d\n", e.Message, e.Offset)
+	fmt.Fprintf(&b, "  %s\n", e.Source)
+	fmt.Fprintf(&b, "  %s^", caretPadding(e.Source, e.Offset))
+
+	if e.Hint != "" {
+		fmt.Fprintf(&b, "\nhint: %s", e.Hint)
+	}
+
+	return b.String()
+}
+
+// caretPadding returns the whitespace needed to put a caret below
+// the rune at offset. tabs are kept so the caret lines up
+func caretPadding(source string, offset int) string {
+	offset = min(max(offset, 0), len(source))
+
+	var b strings.Builder
+	for _, r := range source[:offset] {
+		if r == '\t' {
+			b.WriteRune('\t')
+			continue
+		}
+		b.WriteRune(' ')
+	}
+
+	return b.String()
+}
+
+// operatorHints maps common misspellings to the operator that was meant
+var operatorHints = map[string]string{
+	"=":            "==",
+	"===":          "==",
+	"<>":           "!=",
+	"contains":     "array-contains",
+	"contains-any": "array-contains-any",
+	"containsAny":  "array-contains-any",
+	"notin":        "not-in",
+	"not_in":       "not-in",
+	"nin":          "not-in",
+	"eq":           "==",
+	"ne":           "!=",
+	"neq":          "!=",
+	"gt":           ">",
+	"gte":          ">=",
+	"lt":           "<",
+	"lte":          "<=",
+}
+
+// connectiveHints maps words commonly used to combine conditions to their operator
+var connectiveHints = map[string]string{
+	"and": "&&",
+	"AND": "&&",
+	"or":  "||",
+	"OR":  "||",
+}
+
+// clientOperators are only supported by --filter
+var clientOperators = []string{"=~", "!~", "starts-with", "ends-with"}
+
+func operatorHint(op string) string {
+	if slices.Contains(clientOperators, op) {
+		return fmt.Sprintf("%s is only supported by client-side --filter expressions", op)
+	}
+	if suggestion, found := operatorHints[op]; found {
+		return fmt.Sprintf("use %s instead of %s", suggestion, op)
+	}
+
+	return "use one of ==, !=, <, <=, >, >=, in, not-in, array-contains, array-contains-any"
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilterErrorPositions(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		source string
+
+		offset int
+		hint   string
+	}{
+		{source: `status = "open"`, offset: 7, hint: "use == to compare values"},
+		{source: `status == open`, offset: 10, hint: `wrap strings in quotes, e.g. "open"`},
+		{source: `tags contains "a"`, offset: 5, hint: "use array-contains instead of contains"},
+		{source: `tags contains-any ["a"]`, offset: 5, hint: "use array-contains-any instead of contains-any"},
+		{source: `a notin [1]`, offset: 2, hint: "use not-in instead of notin"},
+		{source: `a <> 1`, offset: 2, hint: "use != instead of <>"},
+		{source: `a == 1 and b == 2`, offset: 7, hint: "combine conditions with && instead of and"},
+		{source: `user-id == 1`, offset: 0, hint: keyHint},
+		{source: `a == foo(1)`, offset: 5, hint: "available functions are bytes(), geopoint(), now(), ref(), timestamp()"},
+		{source: `a == now() - 7x`, offset: 14, hint: durationUnitHint},
+		{source: `a == "open`, offset: 5},
+		{source: `(a == 1`, offset: 7},
+		{source: `a == [1, 2`, offset: 10, hint: "close the list with ]"},
+		{source: `a == 1 & b == 2`, offset: 7},
+		{source: `a ==`, offset: 4},
+		{source: ``, offset: 0, hint: conditionHint},
+		{source: `   `, offset: 0, hint: conditionHint},
+	}
+
+	for _, fixture := range fixtures {
+		_, err := ParseFilter(fixture.source)
+
+		var parseErr *Error
+		if !assert.True(errors.As(err, &parseErr), fixture.source) {
+			continue
+		}
+
+		assert.Equal(fixture.source, parseErr.Source)
+		assert.Equal(fixture.offset, parseErr.Offset, fixture.source)
+		assert.Equal(fixture.hint, parseErr.Hint, fixture.source)
+	}
+}
+
+func TestParseEmptyValueErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, parse := range []func(string) (any, error){
+		func(s string) (any, error) { return ParseValue(s) },
+		func(s string) (any, error) { return ParseValues(s) },
+	} {
+		_, err := parse(" ")
+
+		var parseErr *Error
+		if assert.True(errors.As(err, &parseErr)) {
+			assert.Equal(0, parseErr.Offset)
+			assert.Equal("expected value, got empty input", parseErr.Message)
+		}
+	}
+}
+
+func TestParseAssignmentErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := ParseAssignment(`count == 5`)
+
+	var parseErr *Error
+	assert.True(errors.As(err, &parseErr))
+	assert.Equal(6, parseErr.Offset)
+	assert.Equal("use a single = to assign a value", parseErr.Hint)
+}
+
+func TestErrorString(t *testing.T) {
+	assert := assert.New(t)
+
+	err := &Error{
+		Source:  `status = "open"`,
+		Offset:  7,
+		Message: "expected operator, got assign =",
+		Hint:    "use == to compare values",
+	}
+	assert.Equal("expected operator, got assign = at offset 7\n"+
+		"  status = \"open\"\n"+
+		"         ^\n"+
+		"hint: use == to compare values", err.Error())
+
+	err = &Error{Source: "ä == x", Offset: 6, Message: "invalid value x"}
+	assert.Equal("invalid value x at offset 6\n"+
+		"  ä == x\n"+
+		"       ^", err.Error())
+
+	err = &Error{Source: "\ta ==", Offset: 5, Message: "missing value"}
+	assert.Equal("missing value at offset 5\n"+
+		"  \ta ==\n"+
+		"  \t    ^", err.Error())
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 	"unicode"
 )
@@ -10,9 +11,19 @@ type (
 		reader *strings.Reader
 	}
 
+	// whereLexer extends valueLexer with the tokens needed
+	// for keys, operators and boolean expressions
+	whereLexer struct {
+		*valueLexer
+	}
+
 	token struct {
 		kind  tokenKind
 		value string
+		// pos is the byte offset of the token in the source
+		pos int
+		// message describes why a token is illegal
+		message string
 	}
 
 	tokenKind int
@@ -34,6 +45,16 @@ const (
 	tokenTrue
 	tokenFalse
 	tokenNull
+
+	tokenParenOpen
+	tokenParenClose
+	tokenAnd
+	tokenOr
+	tokenOperator
+	tokenPlus
+	tokenMinus
+	tokenAssign
+	tokenRegex
 )
 
 func newValueLexer(value string) *valueLexer {
@@ -42,6 +63,12 @@ func newValueLexer(value string) *valueLexer {
 	}
 }
 
+func newWhereLexer(source string) *whereLexer {
+	return &whereLexer{
+		valueLexer: newValueLexer(source),
+	}
+}
+
 func (l *valueLexer) read() rune {
 	r, _, err := l.reader.ReadRune()
 	if err != nil {
@@ -55,7 +82,20 @@ func (l *valueLexer) unread() {
 	l.reader.UnreadRune()
 }
 
+// offset returns the byte offset of the next rune
+func (l *valueLexer) offset() int {
+	return int(l.reader.Size()) - l.reader.Len()
+}
+
 func (l *valueLexer) lex() token {
+	pos := l.offset()
+	t := l.lexValueToken()
+	t.pos = pos
+
+	return t
+}
+
+func (l *valueLexer) lexValueToken() token {
 	r := l.read()
 
 	switch r {
@@ -69,7 +109,10 @@ func (l *valueLexer) lex() token {
 		return token{kind: tokenSquareBracketClose, value: "]"}
 	case '"', '\'':
 		l.unread()
-		value := l.lexString()
+		value, terminated := l.lexString()
+		if !terminated {
+			return token{kind: tokenIllegal, message: fmt.Sprintf("unterminated string. missing closing %c", r)}
+		}
 		return token{kind: tokenString, value: value}
 	case '-':
 		l.unread()
@@ -99,7 +142,196 @@ func (l *valueLexer) lex() token {
 		return l.lexNumber()
 	}
 
-	return token{kind: tokenIllegal}
+	return token{kind: tokenIllegal, message: fmt.Sprintf("unexpected character %q", r)}
+}
+
+func (l *whereLexer) lex() token {
+	pos := l.offset()
+	t := l.lexWhereToken()
+	t.pos = pos
+
+	return t
+}
+
+func (l *whereLexer) lexWhereToken() token {
+	r := l.read()
+
+	switch r {
+	case '(':
+		return token{kind: tokenParenOpen, value: "("}
+	case ')':
+		return token{kind: tokenParenClose, value: ")"}
+	case '&', '|':
+		if l.read() != r {
+			l.unread()
+			return token{kind: tokenIllegal, message: fmt.Sprintf("unexpected character %q. expected %c%c", r, r, r)}
+		}
+		if r == '&' {
+			return token{kind: tokenAnd, value: "&&"}
+		}
+		return token{kind: tokenOr, value: "||"}
+	case '=':
+		switch l.read() {
+		case '=':
+			return token{kind: tokenOperator, value: "=="}
+		case '~':
+			return token{kind: tokenOperator, value: "=~"}
+		}
+		l.unread()
+		return token{kind: tokenAssign, value: "="}
+	case '!':
+		switch l.read() {
+		case '=':
+			return token{kind: tokenOperator, value: "!="}
+		case '~':
+			return token{kind: tokenOperator, value: "!~"}
+		}
+		l.unread()
+		return token{kind: tokenIllegal, message: "unexpected character '!'. expected != or !~"}
+	case '/':
+		l.unread()
+		value, terminated := l.lexRegex()
+		if !terminated {
+			return token{kind: tokenIllegal, message: "unterminated regular expression. missing closing /"}
+		}
+		return token{kind: tokenRegex, value: value}
+	case '+':
+		return token{kind: tokenPlus, value: "+"}
+	case '-':
+		next := l.read()
+		l.unread()
+		if !unicode.IsDigit(next) {
+			return token{kind: tokenMinus, value: "-"}
+		}
+
+		t := l.lexNumber()
+		if t.kind == tokenNumber {
+			t.value = "-" + t.value
+		}
+		return t
+	case '<', '>':
+		if l.read() == '=' {
+			return token{kind: tokenOperator, value: string(r) + "="}
+		}
+		l.unread()
+		return token{kind: tokenOperator, value: string(r)}
+	}
+
+	if unicode.IsLetter(r) || r == '_' || r == '`' {
+		l.unread()
+		value := l.lexKey()
+
+		switch value {
+		case "true":
+			return token{kind: tokenTrue, value: "true"}
+		case "false":
+			return token{kind: tokenFalse, value: "false"}
+		case "null":
+			return token{kind: tokenNull, value: "null"}
+		}
+
+		return token{kind: tokenIdent, value: value}
+	}
+
+	l.unread()
+	return l.valueLexer.lex()
+}
+
+// lexKey reads keys like a.b_c or a.`x-id` and word operators like not-in
+func (l *whereLexer) lexKey() string {
+	value := ""
+
+	for {
+		r := l.read()
+
+		if r == '`' {
+			value += string(r) + l.lexQuotedSegment()
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' {
+			value += string(r)
+			continue
+		}
+
+		l.unread()
+		break
+	}
+
+	return value
+}
+
+// lexQuotedSegment reads a backtick quoted key segment including
+// the closing backtick. escape sequences are kept as they are
+func (l *whereLexer) lexQuotedSegment() string {
+	value := ""
+
+	for {
+		r := l.read()
+		if r == rune(0) {
+			return value
+		}
+
+		value += string(r)
+
+		if r == '\\' {
+			if next := l.read(); next != rune(0) {
+				value += string(next)
+			}
+			continue
+		}
+		if r == '`' {
+			return value
+		}
+	}
+}
+
+// lexRegex reads a regular expression like /^a\/b$/i. escaped slashes are
+// unescaped and the flags i, m, s and U are turned into a (?flags) prefix.
+// terminated is false if the input ends before the closing slash
+func (l *whereLexer) lexRegex() (value string, terminated bool) {
+	l.read()
+
+	for {
+		r := l.read()
+		if r == rune(0) {
+			return value, false
+		}
+		if r == '/' {
+			break
+		}
+
+		if r == '\\' {
+			next := l.read()
+			if next == rune(0) {
+				return value, false
+			}
+			if next != '/' {
+				value += string(r)
+			}
+			value += string(next)
+			continue
+		}
+
+		value += string(r)
+	}
+
+	flags := ""
+	for {
+		r := l.read()
+		if r == 'i' || r == 'm' || r == 's' || r == 'U' {
+			flags += string(r)
+			continue
+		}
+
+		l.unread()
+		break
+	}
+
+	if flags != "" {
+		value = fmt.Sprintf("(?%s)%s", flags, value)
+	}
+
+	return value, true
 }
 
 func (l *valueLexer) lexWhitespace() {
@@ -133,21 +365,23 @@ func (l *valueLexer) lexIdent() string {
 	return value
 }
 
-func (l *valueLexer) lexString() string {
+// lexString reads a quoted string. terminated is false
+// if the input ends before the closing quote
+func (l *valueLexer) lexString() (value string, terminated bool) {
 	borderChar := l.read()
-	value := ""
 
 	for {
 		r := l.read()
 
-		if r == rune(0) || r == borderChar {
-			break
+		if r == rune(0) {
+			return value, false
+		}
+		if r == borderChar {
+			return value, true
 		}
 
 		value += string(r)
 	}
-
-	return value
 }
 
 func (l *valueLexer) lexNumber() token {
@@ -166,10 +400,11 @@ func (l *valueLexer) lexNumber() token {
 	}
 
 	if value == "-" {
-		return token{kind: tokenIllegal}
+		return token{kind: tokenIllegal, message: "expected number after '-'"}
 	}
 
 	if r := l.read(); r != '.' {
+		l.unread()
 		return token{kind: tokenNumber, value: value}
 	}
 
@@ -189,7 +424,7 @@ func (l *valueLexer) lexNumber() token {
 
 	// things like 5. without decimal numbers should be illegal
 	if strings.HasSuffix(value, ".") {
-		return token{kind: tokenIllegal}
+		return token{kind: tokenIllegal, message: fmt.Sprintf("missing decimals in number %s", value)}
 	}
 
 	return token{kind: tokenNumber, value: value}
@@ -221,7 +456,39 @@ func (k tokenKind) String() string {
 		return "False"
 	case tokenNull:
 		return "Null"
+	case tokenParenOpen:
+		return "ParenOpen"
+	case tokenParenClose:
+		return "ParenClose"
+	case tokenAnd:
+		return "And"
+	case tokenOr:
+		return "Or"
+	case tokenOperator:
+		return "Operator"
+	case tokenPlus:
+		return "Plus"
+	case tokenMinus:
+		return "Minus"
+	case tokenAssign:
+		return "Assign"
+	case tokenRegex:
+		return "Regex"
 	default:
 		return ""
 	}
 }
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of input"
+	case tokenIllegal:
+		if t.message != "" {
+			return t.message
+		}
+		return "illegal token"
+	default:
+		return fmt.Sprintf("%s %s", strings.ToLower(t.kind.String()), t.value)
+	}
+}
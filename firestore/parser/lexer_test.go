@@ -64,3 +64,30 @@ func TestLexNumbers(t *testing.T) {
 	token = lexer.lex()
 	assert.Equal(token.kind, tokenIllegal)
 }
+
+func TestLexWhere(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		value    string
+		expected token
+	}{
+		{value: `==`, expected: token{kind: tokenOperator, value: "=="}},
+		{value: `=~`, expected: token{kind: tokenOperator, value: "=~"}},
+		{value: `!~`, expected: token{kind: tokenOperator, value: "!~"}},
+		{value: `=`, expected: token{kind: tokenAssign, value: "="}},
+		{value: `!`, expected: token{kind: tokenIllegal, value: ""}},
+		{value: `/a.c/`, expected: token{kind: tokenRegex, value: "a.c"}},
+		{value: `/a\/b\.c/`, expected: token{kind: tokenRegex, value: `a/b\.c`}},
+		{value: `/abc/im`, expected: token{kind: tokenRegex, value: "(?im)abc"}},
+		{value: `/abc`, expected: token{kind: tokenIllegal, value: ""}},
+	}
+
+	for i, fixture := range fixtures {
+		lexer := newWhereLexer(fixture.value)
+		token := lexer.lex()
+
+		assert.Equal(fixture.expected.kind, token.kind, fmt.Sprintf("fixture: %d", i+1))
+		assert.Equal(fixture.expected.value, token.value, fmt.Sprintf("fixture: %d", i+1))
+	}
+}
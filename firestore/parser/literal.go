@@ -0,0 +1,241 @@
+package parser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/steschwa/fq/firestore"
+)
+
+// supported literals:
+//
+//	timestamp("2025-01-01T00:00:00Z")
+//	now(), now() - 7d, now() + 1h30m
+//	ref("users/abc")
+//	geopoint(52.5, 13.4)
+//	bytes("Zm9v")
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  time.Hour * 24,
+	"w":  time.Hour * 24 * 7,
+}
+
+const durationUnitHint = "use one of ms, s, m, h, d, w"
+
+// now is replaced in tests
+var now = time.Now
+
+func (p *whereParser) parseCall() (firestore.Value, error) {
+	nameToken := p.token
+	name := nameToken.value
+	p.advance()
+
+	if p.token.kind != tokenParenOpen {
+		hint := fmt.Sprintf("wrap strings in quotes, e.g. \"%s\"", name)
+		return nil, p.errorAt(nameToken, hint, "invalid value %s. strings must be quoted", name)
+	}
+	if _, found := literalFuncs[name]; !found {
+		return nil, p.errorAt(nameToken, "available functions are "+literalFuncNames(), "unknown function %s()", name)
+	}
+	p.advance()
+
+	var args []token
+	for p.token.kind != tokenParenClose {
+		if len(args) > 0 {
+			if p.token.kind != tokenComma {
+				return nil, p.errorAt(p.token, "", "expected ',' or ')', got %s", p.token.describe())
+			}
+			p.advance()
+		}
+
+		if p.token.kind != tokenString && p.token.kind != tokenNumber {
+			return nil, p.errorAt(p.token, "", "invalid %s() argument %s", name, p.token.describe())
+		}
+
+		args = append(args, p.token)
+		p.advance()
+	}
+	p.advance()
+
+	if name == "now" {
+		if len(args) != 0 {
+			return nil, p.errorAt(args[0], "", "now() takes no arguments")
+		}
+		return p.parseRelativeTime()
+	}
+
+	v, err := literalFuncs[name](args)
+	if err != nil {
+		return nil, p.errorAt(nameToken, "", "%v", err)
+	}
+
+	return v, nil
+}
+
+// literalFuncs maps function names to their parsers. now() has none
+// as it is parsed together with the offsets following it
+var literalFuncs = map[string]func(args []token) (firestore.Value, error){
+	"timestamp": parseTimestamp,
+	"now":       nil,
+	"ref":       parseReference,
+	"geopoint":  parseGeoPoint,
+	"bytes":     parseBytes,
+}
+
+func literalFuncNames() string {
+	names := make([]string, 0, len(literalFuncs))
+	for name := range literalFuncs {
+		names = append(names, name+"()")
+	}
+	slices.Sort(names)
+
+	return strings.Join(names, ", ")
+}
+
+func parseTimestamp(args []token) (firestore.Value, error) {
+	if len(args) != 1 || args[0].kind != tokenString {
+		return nil, fmt.Errorf("timestamp() takes one string argument")
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		t, err := time.Parse(layout, args[0].value)
+		if err == nil {
+			return firestore.NewTimestampValue(t), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid timestamp %s. expected RFC 3339 or YYYY-MM-DD", args[0].value)
+}
+
+// parseRelativeTime parses the offsets following now(), e.g. - 7d + 12h
+func (p *whereParser) parseRelativeTime() (firestore.Value, error) {
+	var offset time.Duration
+
+	for {
+		sign := time.Duration(1)
+		switch p.token.kind {
+		case tokenPlus:
+			p.advance()
+		case tokenMinus:
+			sign = -1
+			p.advance()
+		case tokenNumber:
+			// now()-7d is lexed as now() followed by the number -7
+			if p.token.value[0] != '-' {
+				return firestore.NewRelativeTimeValue(now(), offset), nil
+			}
+			sign = -1
+			p.token.value = p.token.value[1:]
+		default:
+			return firestore.NewRelativeTimeValue(now(), offset), nil
+		}
+
+		d, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+
+		offset += sign * d
+	}
+}
+
+// parseDuration parses durations like 7d or 1h30m
+func (p *whereParser) parseDuration() (time.Duration, error) {
+	if p.token.kind != tokenNumber {
+		return 0, p.errorAt(p.token, "", "expected duration like 7d, got %s", p.token.describe())
+	}
+	amountToken := p.token
+	amount := amountToken.value
+	p.advance()
+
+	if p.token.kind != tokenIdent {
+		return 0, p.errorAt(p.token, durationUnitHint, "missing duration unit after %s", amount)
+	}
+	unitToken := p.token
+	// the lexer reads everything after the first amount as one ident,
+	// e.g. 1h30m is lexed as the number 1 and the ident h30m
+	rest := p.token.value
+	p.advance()
+
+	var duration time.Duration
+	for {
+		n, err := strconv.Atoi(amount)
+		if err != nil || n < 0 {
+			return 0, p.errorAt(amountToken, "", "invalid duration amount %s", amount)
+		}
+
+		i := strings.IndexFunc(rest, unicode.IsDigit)
+		if i == -1 {
+			i = len(rest)
+		}
+
+		unit, found := durationUnits[rest[:i]]
+		if !found {
+			return 0, p.errorAt(unitToken, durationUnitHint, "invalid duration unit %s", rest[:i])
+		}
+		duration += time.Duration(n) * unit
+
+		rest = rest[i:]
+		if rest == "" {
+			return duration, nil
+		}
+
+		i = strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+		if i == -1 {
+			return 0, p.errorAt(unitToken, durationUnitHint, "missing duration unit after %s", rest)
+		}
+		amount, rest = rest[:i], rest[i:]
+	}
+}
+
+func parseReference(args []token) (firestore.Value, error) {
+	if len(args) != 1 || args[0].kind != tokenString {
+		return nil, fmt.Errorf("ref() takes one string argument")
+	}
+
+	path := args[0].value
+	if !firestore.IsDocumentPath(path) {
+		return nil, fmt.Errorf("invalid document path %s", path)
+	}
+
+	return firestore.NewReferenceValue(path), nil
+}
+
+func parseGeoPoint(args []token) (firestore.Value, error) {
+	if len(args) != 2 || args[0].kind != tokenNumber || args[1].kind != tokenNumber {
+		return nil, fmt.Errorf("geopoint() takes two number arguments")
+	}
+
+	latitude, err := strconv.ParseFloat(args[0].value, 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		return nil, fmt.Errorf("invalid latitude %s. must be between -90 and 90", args[0].value)
+	}
+	longitude, err := strconv.ParseFloat(args[1].value, 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		return nil, fmt.Errorf("invalid longitude %s. must be between -180 and 180", args[1].value)
+	}
+
+	return firestore.NewGeoPointValue(latitude, longitude), nil
+}
+
+func parseBytes(args []token) (firestore.Value, error) {
+	if len(args) != 1 || args[0].kind != tokenString {
+		return nil, fmt.Errorf("bytes() takes one base64 string argument")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(args[0].value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 %s: %v", args[0].value, err)
+	}
+
+	return firestore.NewBytesValue(b), nil
+}
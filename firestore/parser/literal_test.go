@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steschwa/fq/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestParseLiterals(t *testing.T) {
+	assert := assert.New(t)
+
+	fixedNow := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fixedNow }
+	defer func() { now = time.Now }()
+
+	fixtures := []struct {
+		source   string
+		expected any
+	}{
+		{source: `timestamp("2025-01-01T00:00:00Z")`, expected: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{source: `timestamp("2025-01-01")`, expected: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{source: `now()`, expected: fixedNow},
+		{source: `now() - 7d`, expected: fixedNow.Add(-7 * 24 * time.Hour)},
+		{source: `now()-7d`, expected: fixedNow.Add(-7 * 24 * time.Hour)},
+		{source: `now() + 1h30m`, expected: fixedNow.Add(90 * time.Minute)},
+		{source: `now() - 1w + 2d`, expected: fixedNow.Add(-5 * 24 * time.Hour)},
+		{source: `now() + 500ms`, expected: fixedNow.Add(500 * time.Millisecond)},
+		{source: `geopoint(52.5, 13.4)`, expected: &latlng.LatLng{Latitude: 52.5, Longitude: 13.4}},
+		{source: `geopoint(-10, 20)`, expected: &latlng.LatLng{Latitude: -10, Longitude: 20}},
+		{source: `bytes("Zm9v")`, expected: []byte("foo")},
+	}
+
+	for _, fixture := range fixtures {
+		v, err := parseValue(fixture.source)
+
+		assert.NoError(err, fixture.source)
+		if err == nil {
+			assert.Equal(fixture.expected, v.Value(), fixture.source)
+		}
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	assert := assert.New(t)
+
+	v, err := parseValue(`ref("users/abc")`)
+	assert.NoError(err)
+	assert.Equal(firestore.NewReferenceValue("users/abc"), v)
+	assert.Equal(`ref("users/abc")`, v.String())
+
+	v, err = parseValue(`[ref("users/abc"), ref("users/def")]`)
+	assert.NoError(err)
+	assert.Equal(firestore.ArrayValue{Values: []firestore.Value{
+		firestore.NewReferenceValue("users/abc"),
+		firestore.NewReferenceValue("users/def"),
+	}}, v)
+}
+
+func TestParseLiteralErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []string{
+		`timestamp()`,
+		`timestamp("yesterday")`,
+		`timestamp(5)`,
+		`now(1)`,
+		`now() - 7`,
+		`now() - 7y`,
+		`now() - 1h30`,
+		`now() -`,
+		`ref("users")`,
+		`ref(5)`,
+		`geopoint(100, 0)`,
+		`geopoint(0, 200)`,
+		`geopoint(1)`,
+		`bytes("not base64!")`,
+		`unknown("foo")`,
+		`timestamp("2025-01-01"`,
+		`open`,
+	}
+
+	for _, fixture := range fixtures {
+		_, err := parseValue(fixture)
+		assert.Error(err, fixture)
+	}
+}
+
+func TestParseFilterWithLiterals(t *testing.T) {
+	assert := assert.New(t)
+
+	filter, err := ParseFilter(`createdAt >= timestamp("2025-01-01T00:00:00Z") && author == ref("users/abc")`)
+	assert.NoError(err)
+	assert.Equal(`createdAt >= timestamp("2025-01-01T00:00:00Z") && author == ref("users/abc")`, filter.String())
+}
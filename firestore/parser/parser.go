@@ -3,7 +3,6 @@ package parser
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -12,39 +11,439 @@ import (
 
 var (
 	errInvalidOperator = errors.New("invalid operator")
-	errNoTokens        = errors.New("no tokens")
+	errInvalidKey      = errors.New("invalid key")
+	errNoTokens        = errors.New("empty input")
 )
 
-var (
-	whereRe = regexp.MustCompile(`^([a-zA-Z._]+) (==|!=|>|<|>=|<=|in|not-in|array-contains-any) (.*)$`)
-)
+// conditionHint is shown for empty conditions
+const conditionHint = `use {KEY} {OPERATOR} {VALUE}, e.g. status == "open"`
+
+type whereParser struct {
+	source string
+	lexer  *whereLexer
+	token  token
+}
 
+// Parse parses a single condition in format {KEY} {OPERATOR} {VALUE}
 func Parse(source string) (firestore.Where, error) {
-	matches := whereRe.FindStringSubmatch(source)
-	if len(matches) != 4 {
-		return firestore.Where{}, fmt.Errorf("invalid where")
+	filter, err := ParseFilter(source)
+	if err != nil {
+		return firestore.Where{}, err
+	}
+
+	where, ok := filter.(firestore.Where)
+	if !ok {
+		return firestore.Where{}, &Error{
+			Source:  source,
+			Message: fmt.Sprintf("expected a single condition, got %s", filter.String()),
+		}
+	}
+
+	return where, nil
+}
+
+// ParseFilter parses conditions combined with && and ||.
+// && binds stronger than || and parentheses can be used for grouping, e.g.
+//
+//	status == "open" && (priority > 3 || assignee == null)
+func ParseFilter(source string) (firestore.Filter, error) {
+	p := newWhereParser(source)
+	if p.token.kind == tokenEOF {
+		return nil, p.errorEmpty("condition", conditionHint)
+	}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenEOF {
+		hint := ""
+		if connective, found := connectiveHints[p.token.value]; found && p.token.kind == tokenIdent {
+			hint = fmt.Sprintf("combine conditions with %s instead of %s", connective, p.token.value)
+		}
+		return nil, p.errorAt(p.token, hint, "unexpected %s", p.token.describe())
+	}
+
+	return filter, nil
+}
+
+// ParseKey parses a field path like a.b or a.`x-id`
+func ParseKey(source string) (firestore.KeyPath, error) {
+	p := newWhereParser(source)
+	if p.token.kind != tokenIdent {
+		return "", p.errorAt(p.token, "", "%v, got %s", errInvalidKey, p.token.describe())
+	}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return "", err
 	}
 
-	key := matches[1]
+	if p.token.kind != tokenEOF {
+		return "", p.errorAt(p.token, keyHint, "unexpected %s after key", p.token.describe())
+	}
+
+	return key, nil
+}
+
+// ParseOrderBy parses an order by field in format {KEY}[:asc|desc].
+// fields without direction are ordered descending if desc is set
+func ParseOrderBy(source string, desc bool) (firestore.OrderBy, error) {
+	key, suffix, offset, found := splitKeySuffix(source)
+	if found {
+		switch strings.ToLower(suffix) {
+		case "asc":
+			desc = false
+		case "desc":
+			desc = true
+		default:
+			return firestore.OrderBy{}, &Error{
+				Source:  source,
+				Offset:  offset,
+				Message: fmt.Sprintf("invalid order direction %s", suffix),
+				Hint:    "use asc or desc",
+			}
+		}
+	}
 
-	op, err := parseOperator(matches[2])
+	path, err := ParseKey(key)
 	if err != nil {
-		return firestore.Where{}, fmt.Errorf("parsing operator: %v", err)
+		return firestore.OrderBy{}, err
+	}
+
+	return firestore.OrderBy{
+		Path:      path,
+		Direction: firestore.GetFirestoreDirection(desc),
+	}, nil
+}
+
+// ParseBucket parses a timestamp field grouped by time in format {KEY}:{BUCKET}
+func ParseBucket(source string) (firestore.GroupKey, error) {
+	key, suffix, offset, found := splitKeySuffix(source)
+	if !found {
+		return firestore.GroupKey{}, &Error{
+			Source:  source,
+			Offset:  len(source),
+			Message: "missing bucket",
+			Hint:    "use {KEY}:{BUCKET}, e.g. createdAt:day",
+		}
 	}
 
-	rawValue := matches[3]
-	value, err := parseValue(rawValue)
+	bucket, err := firestore.ParseBucket(suffix)
 	if err != nil {
-		return firestore.Where{}, fmt.Errorf("parsing value: %v", err)
+		return firestore.GroupKey{}, &Error{
+			Source:  source,
+			Offset:  offset,
+			Message: fmt.Sprintf("invalid bucket %s", suffix),
+			Hint:    "use one of hour, day, week, month, year",
+		}
+	}
+
+	path, err := ParseKey(key)
+	if err != nil {
+		return firestore.GroupKey{}, err
+	}
+
+	return firestore.GroupKey{Field: path, Bucket: bucket}, nil
+}
+
+// splitKeySuffix splits source at the last colon outside of backtick
+// quoted segments. offset is the byte offset of the suffix
+func splitKeySuffix(source string) (key string, suffix string, offset int, found bool) {
+	i := strings.LastIndex(source, ":")
+	if i == -1 || strings.Count(source[:i], "`")%2 != 0 {
+		return source, "", 0, false
+	}
+
+	return source[:i], source[i+1:], i + 1, true
+}
+
+// ParseValue parses a single value like "foo", 5 or [1, 2]
+func ParseValue(source string) (firestore.Value, error) {
+	return parseValue(source)
+}
+
+// ParseValues parses comma separated values like "foo", 5
+func ParseValues(source string) ([]firestore.Value, error) {
+	p := newWhereParser(source)
+	if p.token.kind == tokenEOF {
+		return nil, p.errorEmpty("value", "")
+	}
+
+	var values []firestore.Value
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		if p.token.kind == tokenEOF {
+			return values, nil
+		}
+		if p.token.kind != tokenComma {
+			return nil, p.errorAt(p.token, "", "expected ',', got %s", p.token.describe())
+		}
+		p.advance()
+	}
+}
+
+// ParseAssignment parses an assignment in format {KEY}={VALUE}
+func ParseAssignment(source string) (firestore.KeyPath, firestore.Value, error) {
+	p := newWhereParser(source)
+	if p.token.kind != tokenIdent {
+		return "", nil, p.errorAt(p.token, "", "%v, got %s", errInvalidKey, p.token.describe())
+	}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if p.token.kind != tokenAssign {
+		hint := ""
+		if p.token.value == "==" {
+			hint = "use a single = to assign a value"
+		}
+		return "", nil, p.errorAt(p.token, hint, "expected '=', got %s", p.token.describe())
+	}
+	p.advance()
+
+	if p.token.kind == tokenEOF {
+		return "", nil, p.errorAt(p.token, "", "missing value")
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if p.token.kind != tokenEOF {
+		return "", nil, p.errorAt(p.token, "", "unexpected %s after value", p.token.describe())
+	}
+
+	return key, value, nil
+}
+
+func newWhereParser(source string) *whereParser {
+	p := &whereParser{
+		source: source,
+		lexer:  newWhereLexer(source),
+	}
+	p.advance()
+
+	return p
+}
+
+// errorAt returns an *Error pointing at the start of t
+func (p *whereParser) errorAt(t token, hint string, format string, args ...any) *Error {
+	return &Error{
+		Source:  p.source,
+		Offset:  t.pos,
+		Message: fmt.Sprintf(format, args...),
+		Hint:    hint,
+	}
+}
+
+// errorEmpty returns the error for sources without tokens. it points
+// at the start of the source, which may only contain whitespace
+func (p *whereParser) errorEmpty(expected string, hint string) *Error {
+	return &Error{
+		Source:  p.source,
+		Offset:  0,
+		Message: fmt.Sprintf("expected %s, got %v", expected, errNoTokens),
+		Hint:    hint,
+	}
+}
+
+// advance moves to the next token that is not whitespace
+func (p *whereParser) advance() {
+	for {
+		p.token = p.lexer.lex()
+		if p.token.kind != tokenWhitespace {
+			return
+		}
+	}
+}
+
+func (p *whereParser) parseOr() (firestore.Filter, error) {
+	filter, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []firestore.Filter{filter}
+	for p.token.kind == tokenOr {
+		p.advance()
+
+		filter, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return firestore.OrFilter{Filters: filters}, nil
+}
+
+func (p *whereParser) parseAnd() (firestore.Filter, error) {
+	filter, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []firestore.Filter{filter}
+	for p.token.kind == tokenAnd {
+		p.advance()
+
+		filter, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return firestore.AndFilter{Filters: filters}, nil
+}
+
+func (p *whereParser) parsePrimary() (firestore.Filter, error) {
+	if p.token.kind != tokenParenOpen {
+		return p.parseCondition()
+	}
+
+	p.advance()
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenParenClose {
+		return nil, p.errorAt(p.token, "", "expected ')', got %s", p.token.describe())
+	}
+	p.advance()
+
+	return filter, nil
+}
+
+func (p *whereParser) parseCondition() (firestore.Filter, error) {
+	if p.token.kind != tokenIdent {
+		return nil, p.errorAt(p.token, "", "expected key, got %s", p.token.describe())
+	}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+
+	opToken := p.token
+	switch opToken.kind {
+	case tokenAssign:
+		return nil, p.errorAt(opToken, "use == to compare values", "expected operator, got %s", opToken.describe())
+	case tokenOperator, tokenIdent:
+	default:
+		return nil, p.errorAt(opToken, "", "expected operator, got %s", opToken.describe())
+	}
+
+	op, err := parseOperator(opToken.value)
+	if err != nil {
+		return nil, p.errorAt(opToken, operatorHint(opToken.value), "%v %s", err, opToken.value)
+	}
+	p.advance()
+
+	// <> is lexed as the operators < and >
+	if opToken.value == "<" && p.token.kind == tokenOperator && p.token.value == ">" {
+		return nil, p.errorAt(opToken, operatorHint("<>"), "%v <>", errInvalidOperator)
+	}
+
+	if p.token.kind == tokenEOF {
+		return nil, p.errorAt(p.token, "", "missing value after %s", opToken.value)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
 	}
 
 	return firestore.Where{
-		Key:      firestore.KeyPath(key),
+		Key:      key,
 		Operator: op,
 		Value:    value,
 	}, nil
 }
 
+func (p *whereParser) parseValue() (firestore.Value, error) {
+	if p.token.kind == tokenSquareBracketOpen {
+		return p.parseList()
+	}
+
+	return p.parseScalar()
+}
+
+// parseScalar parses a single literal like "foo", 5 or timestamp("...")
+func (p *whereParser) parseScalar() (firestore.Value, error) {
+	if p.token.kind == tokenIdent {
+		return p.parseCall()
+	}
+
+	v, err := parseValueToken(p.token)
+	if err != nil {
+		return nil, p.errorAt(p.token, "", "%v", err)
+	}
+	p.advance()
+
+	return v, nil
+}
+
+func (p *whereParser) parseList() (firestore.Value, error) {
+	p.advance()
+
+	arrayValue := firestore.NewArrayValue()
+	for p.token.kind != tokenSquareBracketClose {
+		if p.token.kind == tokenEOF {
+			return nil, p.errorAt(p.token, "close the list with ]", "unterminated list")
+		}
+
+		if len(arrayValue.Values) > 0 {
+			if p.token.kind != tokenComma {
+				return nil, p.errorAt(p.token, "", "expected ',' or ']', got %s", p.token.describe())
+			}
+			p.advance()
+		}
+
+		v, err := p.parseScalar()
+		if err != nil {
+			return nil, err
+		}
+
+		arrayValue.Add(v)
+	}
+	p.advance()
+
+	return arrayValue, nil
+}
+
+const keyHint = "quote segments with special characters in backticks, e.g. `user-id`"
+
+// parseKey parses the current ident token as key and advances
+func (p *whereParser) parseKey() (firestore.KeyPath, error) {
+	key, err := firestore.ParseKeyPath(p.token.value)
+	if err != nil {
+		return "", p.errorAt(p.token, keyHint, "%v: %v", errInvalidKey, err)
+	}
+	p.advance()
+
+	return key, nil
+}
+
 func parseOperator(op string) (firestore.Operator, error) {
 	switch op {
 	case "==":
@@ -65,57 +464,29 @@ func parseOperator(op string) (firestore.Operator, error) {
 		return firestore.NotIn, nil
 	case "array-contains-any":
 		return firestore.ArrayContainsAny, nil
+	case "array-contains":
+		return firestore.ArrayContains, nil
 	default:
 		return firestore.Operator(0), errInvalidOperator
 	}
 }
 
 func parseValue(value string) (firestore.Value, error) {
-	lexer := newValueLexer(value)
-	var tokens []token
-	for {
-		token := lexer.lex()
-		if token.kind == tokenEOF {
-			break
-		} else if token.kind == tokenIllegal {
-			return nil, fmt.Errorf("illegal token")
-		} else if token.kind == tokenWhitespace {
-			continue
-		} else if token.kind == tokenComma {
-			continue
-		}
-
-		tokens = append(tokens, token)
+	p := newWhereParser(value)
+	if p.token.kind == tokenEOF {
+		return nil, p.errorEmpty("value", "")
 	}
 
-	if len(tokens) == 0 {
-		return nil, errNoTokens
-	}
-
-	if len(tokens) == 1 {
-		v, err := parseValueToken(tokens[0])
-		if err != nil {
-			return nil, fmt.Errorf("parsing token: %v", err)
-		}
-
-		return v, nil
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
 	}
 
-	var (
-		firstToken = tokens[0]
-		lastToken  = tokens[len(tokens)-1]
-	)
-	if firstToken.kind == tokenSquareBracketOpen && lastToken.kind == tokenSquareBracketClose {
-		actualTokens := tokens[1 : len(tokens)-1]
-		v, err := parseListValueTokens(actualTokens)
-		if err != nil {
-			return nil, fmt.Errorf("parsing token list: %v", err)
-		}
-
-		return v, nil
+	if p.token.kind != tokenEOF {
+		return nil, p.errorAt(p.token, "", "unexpected %s after value", p.token.describe())
 	}
 
-	return nil, fmt.Errorf("invalid value")
+	return v, nil
 }
 
 func parseValueToken(token token) (firestore.Value, error) {
@@ -146,19 +517,5 @@ func parseValueToken(token token) (firestore.Value, error) {
 		return firestore.NewIntValue(v), nil
 	}
 
-	return nil, fmt.Errorf("invalid token: %s (%s)", token.kind.String(), token.value)
-}
-
-func parseListValueTokens(tokens []token) (firestore.ArrayValue, error) {
-	arrayValue := firestore.ArrayValue{}
-	for _, token := range tokens {
-		v, err := parseValueToken(token)
-		if err != nil {
-			return firestore.ArrayValue{}, fmt.Errorf("parsing list token: %v", err)
-		}
-
-		arrayValue.Add(v)
-	}
-
-	return arrayValue, nil
+	return nil, fmt.Errorf("expected value, got %s", token.describe())
 }
@@ -102,7 +102,237 @@ func TestParseOperator(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(o, firestore.ArrayContainsAny)
 
+	o, err = parseOperator("array-contains")
+	assert.NoError(err)
+	assert.Equal(o, firestore.ArrayContains)
+
 	o, err = parseOperator("")
 	assert.Error(err)
 	assert.ErrorIs(err, errInvalidOperator)
 }
+
+func TestParseValues(t *testing.T) {
+	assert := assert.New(t)
+
+	values, err := ParseValues(`42, "foo", [1, 2], true`)
+	assert.NoError(err)
+	if assert.Len(values, 4) {
+		assert.Equal(42, values[0].Value())
+		assert.Equal("foo", values[1].Value())
+		assert.Equal([]any{1, 2}, values[2].Value())
+		assert.Equal(true, values[3].Value())
+	}
+
+	values, err = ParseValues(`"abc"`)
+	assert.NoError(err)
+	assert.Len(values, 1)
+
+	_, err = ParseValues(``)
+	assert.Error(err)
+
+	_, err = ParseValues(`1 2`)
+	assert.Error(err)
+
+	_, err = ParseValues(`1,`)
+	assert.Error(err)
+}
+
+func TestParseOrderBy(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		source string
+		desc   bool
+
+		path      string
+		direction string
+	}{
+		{source: "age", path: "age", direction: "asc"},
+		{source: "age", desc: true, path: "age", direction: "desc"},
+		{source: "priority:desc", path: "priority", direction: "desc"},
+		{source: "createdAt:ASC", desc: true, path: "createdAt", direction: "asc"},
+		{source: "meta.`a:b`", path: "meta.`a:b`", direction: "asc"},
+		{source: "`a:b`.c:desc", path: "`a:b`.c", direction: "desc"},
+	}
+
+	for _, fixture := range fixtures {
+		o, err := ParseOrderBy(fixture.source, fixture.desc)
+		assert.NoError(err, fixture.source)
+
+		assert.Equal(fixture.path, string(o.Path))
+		assert.Equal(fixture.path+" "+fixture.direction, o.String())
+	}
+
+	_, err := ParseOrderBy("age:down", false)
+	var parseErr *Error
+	if assert.ErrorAs(err, &parseErr) {
+		assert.Equal(4, parseErr.Offset)
+		assert.Equal("use asc or desc", parseErr.Hint)
+	}
+
+	_, err = ParseOrderBy(":desc", false)
+	assert.Error(err)
+}
+
+func TestParseBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := ParseBucket("createdAt:day")
+	assert.NoError(err)
+	assert.Equal(firestore.GroupKey{Field: "createdAt", Bucket: firestore.BucketDay}, key)
+
+	key, err = ParseBucket("meta.`a:b`:MONTH")
+	assert.NoError(err)
+	assert.Equal(firestore.GroupKey{Field: "meta.`a:b`", Bucket: firestore.BucketMonth}, key)
+
+	_, err = ParseBucket("createdAt")
+	assert.Error(err)
+
+	_, err = ParseBucket("createdAt:decade")
+	var parseErr *Error
+	if assert.ErrorAs(err, &parseErr) {
+		assert.Equal(10, parseErr.Offset)
+	}
+}
+
+func TestParseAssignment(t *testing.T) {
+	assert := assert.New(t)
+
+	key, value, err := ParseAssignment(`profile.name="foo"`)
+	assert.NoError(err)
+	assert.Equal(firestore.KeyPath("profile.name"), key)
+	assert.Equal(firestore.NewStringValue("foo"), value)
+
+	key, value, err = ParseAssignment(`counter = 5`)
+	assert.NoError(err)
+	assert.Equal(firestore.KeyPath("counter"), key)
+	assert.Equal(firestore.NewIntValue(5), value)
+
+	_, _, err = ParseAssignment(`counter`)
+	assert.Error(err)
+
+	_, _, err = ParseAssignment(`=5`)
+	assert.Error(err)
+
+	_, _, err = ParseAssignment(`a..b=5`)
+	assert.Error(err)
+
+	_, _, err = ParseAssignment(`counter=`)
+	assert.Error(err)
+}
+
+func TestParseFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		source   string
+		expected string
+	}{
+		{source: `foo == "bar"`, expected: `foo == "bar"`},
+		{source: `foo=="bar"`, expected: `foo == "bar"`},
+		{source: `a == 1 && b == 2`, expected: `a == 1 && b == 2`},
+		{source: `a == 1 || b == 2 && c == 3`, expected: `a == 1 || (b == 2 && c == 3)`},
+		{source: `(a == 1 || b == 2) && c == 3`, expected: `(a == 1 || b == 2) && c == 3`},
+		{source: `status == "open" && (priority > 3 || assignee == null)`, expected: `status == "open" && (priority > 3 || assignee == null)`},
+		{source: `((a in [1, 2]))`, expected: `a in [1, 2]`},
+		{source: `a not-in ["x"] || b array-contains-any [true]`, expected: `a not-in ["x"] || b array-contains-any [true]`},
+	}
+
+	for i, fixture := range fixtures {
+		filter, err := ParseFilter(fixture.source)
+
+		assert.NoError(err, "index %d", i)
+		if err == nil {
+			assert.Equal(fixture.expected, filter.String(), "index %d", i)
+		}
+	}
+}
+
+func TestParseFilterTree(t *testing.T) {
+	assert := assert.New(t)
+
+	filter, err := ParseFilter(`a == 1 && (b == 2 || c == 3)`)
+	assert.NoError(err)
+
+	and, ok := filter.(firestore.AndFilter)
+	assert.True(ok)
+	assert.Len(and.Filters, 2)
+	assert.IsType(firestore.Where{}, and.Filters[0])
+
+	or, ok := and.Filters[1].(firestore.OrFilter)
+	assert.True(ok)
+	assert.Len(or.Filters, 2)
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []string{
+		``,
+		`foo`,
+		`foo ==`,
+		`foo = "bar"`,
+		`foo == "bar" &&`,
+		`foo == "bar" & bar == 1`,
+		`(foo == "bar"`,
+		`foo == "bar")`,
+		`foo == "bar" bar == 1`,
+		`foo == [1 2]`,
+		`foo == [1,`,
+		`1 == foo`,
+		`foo contains 1`,
+	}
+
+	for _, fixture := range fixtures {
+		_, err := ParseFilter(fixture)
+		assert.Error(err, fixture)
+	}
+
+	_, err := Parse(`a == 1 && b == 2`)
+	assert.Error(err)
+}
+
+func TestParseQuotedKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	w, err := Parse("meta.`x-id` == \"abc\"")
+	assert.NoError(err)
+	assert.Equal([]string{"meta", "x-id"}, w.Key.Segments())
+
+	w, err = Parse("items.`0`.sku == 'abc'")
+	assert.NoError(err)
+	assert.Equal([]string{"items", "0", "sku"}, w.Key.Segments())
+
+	w, err = Parse("address2 != null")
+	assert.NoError(err)
+	assert.Equal([]string{"address2"}, w.Key.Segments())
+
+	_, err = Parse("meta.x-id == 'abc'")
+	assert.Error(err)
+
+	key, err := ParseKey("`a b`.c")
+	assert.NoError(err)
+	assert.Equal([]string{"a b", "c"}, key.Segments())
+
+	key, value, err := ParseAssignment("`a=b`=5")
+	assert.NoError(err)
+	assert.Equal([]string{"a=b"}, key.Segments())
+	assert.Equal(firestore.NewIntValue(5), value)
+}
+
+func TestParseArrayContains(t *testing.T) {
+	assert := assert.New(t)
+
+	w, err := Parse(`tags array-contains "go"`)
+	assert.NoError(err)
+	assert.Equal(firestore.ArrayContains, w.Operator)
+	assert.Equal("array-contains", w.Operator.String())
+
+	w, err = Parse(`tags array-contains-any ["go", "rust"]`)
+	assert.NoError(err)
+	assert.Equal(firestore.ArrayContainsAny, w.Operator)
+
+	w, err = Parse(`__name__ in ["abc", "def"]`)
+	assert.NoError(err)
+	assert.Equal(firestore.DocumentID, w.Key)
+}
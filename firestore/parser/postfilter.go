@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/steschwa/fq/firestore"
+)
+
+const postFuncHint = "use exists(KEY), missing(KEY) or lower(KEY)"
+
+// ParsePostFilter parses client side conditions combined with && and ||.
+// besides the --where operators they support regular expressions, substrings
+// and the functions exists, missing and lower, e.g.
+//
+//	email =~ /@example\.com$/i && (missing(deletedAt) || lower(name) contains "smith")
+func ParsePostFilter(source string) (firestore.PostFilter, error) {
+	p := newWhereParser(source)
+	if p.token.kind == tokenEOF {
+		return nil, p.errorEmpty("condition", conditionHint)
+	}
+
+	filter, err := p.parsePostOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenEOF {
+		hint := ""
+		if connective, found := connectiveHints[p.token.value]; found && p.token.kind == tokenIdent {
+			hint = fmt.Sprintf("combine conditions with %s instead of %s", connective, p.token.value)
+		}
+		return nil, p.errorAt(p.token, hint, "unexpected %s", p.token.describe())
+	}
+
+	return filter, nil
+}
+
+func (p *whereParser) parsePostOr() (firestore.PostFilter, error) {
+	filter, err := p.parsePostAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []firestore.PostFilter{filter}
+	for p.token.kind == tokenOr {
+		p.advance()
+
+		filter, err := p.parsePostAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return firestore.PostOrFilter{Filters: filters}, nil
+}
+
+func (p *whereParser) parsePostAnd() (firestore.PostFilter, error) {
+	filter, err := p.parsePostPrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []firestore.PostFilter{filter}
+	for p.token.kind == tokenAnd {
+		p.advance()
+
+		filter, err := p.parsePostPrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return firestore.PostAndFilter{Filters: filters}, nil
+}
+
+func (p *whereParser) parsePostPrimary() (firestore.PostFilter, error) {
+	if p.token.kind != tokenParenOpen {
+		return p.parsePostCondition()
+	}
+
+	p.advance()
+	filter, err := p.parsePostOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind != tokenParenClose {
+		return nil, p.errorAt(p.token, "", "expected ')', got %s", p.token.describe())
+	}
+	p.advance()
+
+	return filter, nil
+}
+
+func (p *whereParser) parsePostCondition() (firestore.PostFilter, error) {
+	if p.token.kind != tokenIdent {
+		return nil, p.errorAt(p.token, "", "expected key, got %s", p.token.describe())
+	}
+
+	nameToken := p.token
+	p.advance()
+
+	if p.token.kind == tokenParenOpen {
+		return p.parsePostCall(nameToken)
+	}
+
+	key, err := p.postFilterKey(nameToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parsePostComparison(key, false)
+}
+
+// parsePostCall parses exists(KEY), missing(KEY) and lower(KEY) {OPERATOR} {VALUE}.
+// the current token is the opening parenthesis after the function name
+func (p *whereParser) parsePostCall(nameToken token) (firestore.PostFilter, error) {
+	name := nameToken.value
+	if name != "exists" && name != "missing" && name != "lower" {
+		return nil, p.errorAt(nameToken, postFuncHint, "unknown function %s()", name)
+	}
+	p.advance()
+
+	if p.token.kind != tokenIdent {
+		return nil, p.errorAt(p.token, "", "expected key in %s(), got %s", name, p.token.describe())
+	}
+	key, err := p.postFilterKey(p.token)
+	if err != nil {
+		return nil, err
+	}
+	p.advance()
+
+	if p.token.kind != tokenParenClose {
+		return nil, p.errorAt(p.token, "", "expected ')', got %s", p.token.describe())
+	}
+	p.advance()
+
+	switch name {
+	case "exists":
+		return firestore.ExistsFilter{Key: key}, nil
+	case "missing":
+		return firestore.MissingFilter{Key: key}, nil
+	default:
+		return p.parsePostComparison(key, true)
+	}
+}
+
+func (p *whereParser) parsePostComparison(key firestore.KeyPath, lower bool) (firestore.PostFilter, error) {
+	opToken := p.token
+	switch opToken.kind {
+	case tokenAssign:
+		return nil, p.errorAt(opToken, "use == to compare values", "expected operator, got %s", opToken.describe())
+	case tokenOperator, tokenIdent:
+	default:
+		return nil, p.errorAt(opToken, "", "expected operator, got %s", opToken.describe())
+	}
+
+	op, err := parsePostOperator(opToken.value)
+	if err != nil {
+		return nil, p.errorAt(opToken, postOperatorHint, "%v %s", err, opToken.value)
+	}
+	p.advance()
+
+	if p.token.kind == tokenEOF {
+		return nil, p.errorAt(p.token, "", "missing value after %s", opToken.value)
+	}
+
+	condition := firestore.PostCondition{
+		Key:      key,
+		Lower:    lower,
+		Operator: op,
+	}
+
+	if op.IsMatch() {
+		if p.token.kind != tokenRegex {
+			hint := ""
+			if p.token.kind != tokenIllegal {
+				hint = "wrap the pattern in slashes, e.g. /^a.*z$/"
+			}
+			return nil, p.errorAt(p.token, hint, "expected regular expression after %s, got %s", opToken.value, p.token.describe())
+		}
+
+		pattern, err := regexp.Compile(p.token.value)
+		if err != nil {
+			return nil, p.errorAt(p.token, "", "invalid regular expression: %v", err)
+		}
+		p.advance()
+
+		condition.Pattern = pattern
+		return condition, nil
+	}
+
+	valueToken := p.token
+	condition.Value, err = p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, isList := condition.Value.(firestore.ArrayValue); (op == firestore.PostIn || op == firestore.PostNotIn) && !isList {
+		return nil, p.errorAt(valueToken, "use a list, e.g. [1, 2]", "%s expects a list, got %s", op, condition.Value.String())
+	}
+
+	return condition, nil
+}
+
+// postFilterKey parses t as key. document ids aren't part of the document data
+func (p *whereParser) postFilterKey(t token) (firestore.KeyPath, error) {
+	key, err := firestore.ParseKeyPath(t.value)
+	if err != nil {
+		return "", p.errorAt(t, keyHint, "%v: %v", errInvalidKey, err)
+	}
+	if key == firestore.DocumentID {
+		return "", p.errorAt(t, "use --where or --id-prefix", "%s can't be used in client-side filters", key)
+	}
+
+	return key, nil
+}
+
+const postOperatorHint = "use one of ==, !=, <, <=, >, >=, in, not-in, contains, starts-with, ends-with, =~, !~"
+
+func parsePostOperator(op string) (firestore.PostOperator, error) {
+	switch op {
+	case "==":
+		return firestore.PostEq, nil
+	case "!=":
+		return firestore.PostNeq, nil
+	case ">":
+		return firestore.PostGt, nil
+	case "<":
+		return firestore.PostLt, nil
+	case ">=":
+		return firestore.PostGte, nil
+	case "<=":
+		return firestore.PostLte, nil
+	case "in":
+		return firestore.PostIn, nil
+	case "not-in":
+		return firestore.PostNotIn, nil
+	case "contains":
+		return firestore.PostContains, nil
+	case "starts-with":
+		return firestore.PostStartsWith, nil
+	case "ends-with":
+		return firestore.PostEndsWith, nil
+	case "=~":
+		return firestore.PostMatches, nil
+	case "!~":
+		return firestore.PostNotMatches, nil
+	default:
+		return firestore.PostOperator(0), errInvalidOperator
+	}
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/steschwa/fq/firestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePostFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		source   string
+		expected string
+	}{
+		{source: `email =~ /@example\.com$/`, expected: `email =~ /@example\.com$/`},
+		{source: `email !~ /^admin@/i`, expected: `email !~ /(?i)^admin@/`},
+		{source: `url =~ /^https:\/\//`, expected: `url =~ /^https:\/\//`},
+		{source: `exists(profile.avatar)`, expected: `exists(profile.avatar)`},
+		{source: `missing(deletedAt)`, expected: `missing(deletedAt)`},
+		{source: `lower(name) contains "smith"`, expected: `lower(name) contains "smith"`},
+		{source: `name starts-with 'J' || name ends-with "son"`, expected: `name starts-with "J" || name ends-with "son"`},
+		{source: `age >= 18 && (missing(deletedAt) || status in ["open", "new"])`, expected: `age >= 18 && (missing(deletedAt) || status in ["open", "new"])`},
+		{source: "meta.`x-id` == 5", expected: "meta.`x-id` == 5"},
+	}
+
+	for _, fixture := range fixtures {
+		filter, err := ParsePostFilter(fixture.source)
+		if assert.NoError(err, fixture.source) {
+			assert.Equal(fixture.expected, filter.String())
+		}
+	}
+}
+
+func TestParsePostFilterCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	filter, err := ParsePostFilter(`lower(name) contains "smith"`)
+	assert.NoError(err)
+	assert.Equal(firestore.PostCondition{
+		Key:      "name",
+		Lower:    true,
+		Operator: firestore.PostContains,
+		Value:    firestore.NewStringValue("smith"),
+	}, filter)
+
+	filter, err = ParsePostFilter(`email =~ /@EXAMPLE\.com$/i`)
+	assert.NoError(err)
+	assert.True(filter.Match(map[string]any{"email": "jane@example.com"}))
+	assert.False(filter.Match(map[string]any{"email": "jane@example.org"}))
+}
+
+func TestParsePostFilterErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		source string
+
+		offset int
+		hint   string
+	}{
+		{source: `email =~ "@example.com"`, offset: 9, hint: "wrap the pattern in slashes, e.g. /^a.*z$/"},
+		{source: `email =~ /(/`, offset: 9},
+		{source: `email =~ /abc`, offset: 9},
+		{source: `upper(name) == "A"`, offset: 0, hint: postFuncHint},
+		{source: `exists(name`, offset: 11},
+		{source: `name = "A"`, offset: 5, hint: "use == to compare values"},
+		{source: `name like "A"`, offset: 5, hint: postOperatorHint},
+		{source: `status in "open"`, offset: 10, hint: "use a list, e.g. [1, 2]"},
+		{source: `__name__ == "abc"`, offset: 0, hint: "use --where or --id-prefix"},
+		{source: `a == 1 and b == 2`, offset: 7, hint: "combine conditions with && instead of and"},
+	}
+
+	for _, fixture := range fixtures {
+		_, err := ParsePostFilter(fixture.source)
+
+		var parseErr *Error
+		if assert.ErrorAs(err, &parseErr, fixture.source) {
+			assert.Equal(fixture.offset, parseErr.Offset, fixture.source)
+			assert.Equal(fixture.hint, parseErr.Hint, fixture.source)
+		}
+	}
+
+	_, err := ParsePostFilter(``)
+
+	var parseErr *Error
+	if assert.ErrorAs(err, &parseErr) {
+		assert.Equal(0, parseErr.Offset)
+		assert.Equal(conditionHint, parseErr.Hint)
+	}
+}
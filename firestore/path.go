@@ -42,3 +42,14 @@ func isValidPathSegments(segments []string) bool {
 
 	return true
 }
+
+// relativePath strips the "projects/{p}/databases/{d}/documents/" prefix
+// from a fully qualified document path
+func relativePath(path string) string {
+	_, relative, found := strings.Cut(path, "/documents/")
+	if !found {
+		return path
+	}
+
+	return relative
+}
@@ -0,0 +1,377 @@
+package firestore
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// post filters run on the client after firestore returned the documents of
+// the --where filters. they support conditions firestore can't evaluate
+
+type (
+	// PostFilter is checked against the data of every loaded document
+	PostFilter interface {
+		String() string
+		Match(data map[string]any) bool
+		// Keys returns the fields the filter reads
+		Keys() []KeyPath
+	}
+
+	PostAndFilter struct {
+		Filters []PostFilter
+	}
+	PostOrFilter struct {
+		Filters []PostFilter
+	}
+
+	// ExistsFilter matches documents with a field at Key, even if it is null
+	ExistsFilter struct {
+		Key KeyPath
+	}
+	// MissingFilter matches documents without a field at Key
+	MissingFilter struct {
+		Key KeyPath
+	}
+
+	PostOperator int
+
+	// PostCondition compares the field at Key with Value. the match operators
+	// use Pattern instead. documents without the field never match
+	PostCondition struct {
+		Key KeyPath
+		// Lower compares the lower cased field, e.g. lower(name) contains "smith".
+		// fields which aren't strings don't match then
+		Lower    bool
+		Operator PostOperator
+		Value    Value
+		Pattern  *regexp.Regexp
+	}
+
+	// PostFilterStats counts the documents checked by post filters
+	PostFilterStats struct {
+		Scanned int
+		Matched int
+	}
+)
+
+const (
+	PostEq         PostOperator = iota + 1 // ==
+	PostNeq                                // !=
+	PostGt                                 // >
+	PostLt                                 // <
+	PostGte                                // >=
+	PostLte                                // <=
+	PostIn                                 // in
+	PostNotIn                              // not-in
+	PostContains                           // contains
+	PostStartsWith                         // starts-with
+	PostEndsWith                           // ends-with
+	PostMatches                            // =~
+	PostNotMatches                         // !~
+)
+
+var (
+	_ PostFilter = PostAndFilter{}
+	_ PostFilter = PostOrFilter{}
+	_ PostFilter = ExistsFilter{}
+	_ PostFilter = MissingFilter{}
+	_ PostFilter = PostCondition{}
+)
+
+func (o PostOperator) String() string {
+	switch o {
+	case PostEq:
+		return "=="
+	case PostNeq:
+		return "!="
+	case PostGt:
+		return ">"
+	case PostLt:
+		return "<"
+	case PostGte:
+		return ">="
+	case PostLte:
+		return "<="
+	case PostIn:
+		return "in"
+	case PostNotIn:
+		return "not-in"
+	case PostContains:
+		return "contains"
+	case PostStartsWith:
+		return "starts-with"
+	case PostEndsWith:
+		return "ends-with"
+	case PostMatches:
+		return "=~"
+	case PostNotMatches:
+		return "!~"
+	default:
+		return ""
+	}
+}
+
+// IsMatch reports whether the operator takes a regular expression
+func (o PostOperator) IsMatch() bool {
+	return o == PostMatches || o == PostNotMatches
+}
+
+func (f PostAndFilter) Match(data map[string]any) bool {
+	return MatchAll(f.Filters, data)
+}
+func (f PostAndFilter) Keys() []KeyPath {
+	return postFilterKeys(f.Filters)
+}
+func (f PostAndFilter) String() string {
+	return joinPostFilters(f.Filters, " && ")
+}
+
+func (f PostOrFilter) Match(data map[string]any) bool {
+	for _, filter := range f.Filters {
+		if filter.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+func (f PostOrFilter) Keys() []KeyPath {
+	return postFilterKeys(f.Filters)
+}
+func (f PostOrFilter) String() string {
+	return joinPostFilters(f.Filters, " || ")
+}
+
+func (f ExistsFilter) Match(data map[string]any) bool {
+	_, found := lookupField(data, f.Key.Segments())
+	return found
+}
+func (f ExistsFilter) Keys() []KeyPath {
+	return []KeyPath{f.Key}
+}
+func (f ExistsFilter) String() string {
+	return fmt.Sprintf("exists(%s)", f.Key)
+}
+
+func (f MissingFilter) Match(data map[string]any) bool {
+	_, found := lookupField(data, f.Key.Segments())
+	return !found
+}
+func (f MissingFilter) Keys() []KeyPath {
+	return []KeyPath{f.Key}
+}
+func (f MissingFilter) String() string {
+	return fmt.Sprintf("missing(%s)", f.Key)
+}
+
+func (c PostCondition) Match(data map[string]any) bool {
+	field, found := lookupField(data, c.Key.Segments())
+	if !found {
+		return false
+	}
+
+	if c.Lower {
+		s, ok := field.(string)
+		if !ok {
+			return false
+		}
+		field = strings.ToLower(s)
+	}
+
+	if c.Operator.IsMatch() {
+		s, ok := field.(string)
+		if !ok {
+			return false
+		}
+		return c.Pattern.MatchString(s) == (c.Operator == PostMatches)
+	}
+
+	want := filterValue(c.Value.Value())
+	field = filterValue(field)
+
+	switch c.Operator {
+	case PostEq:
+		return equalFilterValues(field, want)
+	case PostNeq:
+		return !equalFilterValues(field, want)
+	case PostIn, PostNotIn:
+		list, _ := want.([]any)
+		found := slices.ContainsFunc(list, func(item any) bool {
+			return equalFilterValues(field, item)
+		})
+		return found == (c.Operator == PostIn)
+	case PostContains:
+		switch field := field.(type) {
+		case string:
+			s, ok := want.(string)
+			return ok && strings.Contains(field, s)
+		case []any:
+			return slices.ContainsFunc(field, func(item any) bool {
+				return equalFilterValues(item, want)
+			})
+		default:
+			return false
+		}
+	case PostStartsWith, PostEndsWith:
+		s, ok := field.(string)
+		prefix, isString := want.(string)
+		if !ok || !isString {
+			return false
+		}
+		if c.Operator == PostStartsWith {
+			return strings.HasPrefix(s, prefix)
+		}
+		return strings.HasSuffix(s, prefix)
+	}
+
+	order, ok := compareFilterValues(field, want)
+	if !ok {
+		return false
+	}
+
+	switch c.Operator {
+	case PostGt:
+		return order > 0
+	case PostLt:
+		return order < 0
+	case PostGte:
+		return order >= 0
+	case PostLte:
+		return order <= 0
+	default:
+		return false
+	}
+}
+func (c PostCondition) Keys() []KeyPath {
+	return []KeyPath{c.Key}
+}
+func (c PostCondition) String() string {
+	key := string(c.Key)
+	if c.Lower {
+		key = fmt.Sprintf("lower(%s)", key)
+	}
+
+	if c.Operator.IsMatch() {
+		pattern := strings.ReplaceAll(c.Pattern.String(), "/", `\/`)
+		return fmt.Sprintf("%s %s /%s/", key, c.Operator, pattern)
+	}
+
+	return fmt.Sprintf("%s %s %s", key, c.Operator, c.Value.String())
+}
+
+// String reports the counts and makes clear the filtering happened on the client
+func (s PostFilterStats) String() string {
+	return fmt.Sprintf("client-side filter: %d of %d scanned documents matched", s.Matched, s.Scanned)
+}
+
+// MatchAll reports whether data matches all filters
+func MatchAll(filters []PostFilter, data map[string]any) bool {
+	for _, filter := range filters {
+		if !filter.Match(data) {
+			return false
+		}
+	}
+	return true
+}
+
+func postFilterKeys(filters []PostFilter) []KeyPath {
+	var keys []KeyPath
+	for _, filter := range filters {
+		for _, key := range filter.Keys() {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+func joinPostFilters(filters []PostFilter, sep string) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		switch filter.(type) {
+		case PostAndFilter, PostOrFilter:
+			parts[i] = fmt.Sprintf("(%s)", filter.String())
+		default:
+			parts[i] = filter.String()
+		}
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// filterValue converts document fields and filter values to comparable
+// values. ints become int64 and references their relative path
+func filterValue(value any) any {
+	switch value := value.(type) {
+	case int:
+		return int64(value)
+	case *firestore.DocumentRef:
+		return RefPath(relativePath(value.Path))
+	case []any:
+		out := make([]any, len(value))
+		for i, v := range value {
+			out[i] = filterValue(v)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for key, v := range value {
+			out[key] = filterValue(v)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func equalFilterValues(a, b any) bool {
+	if order, ok := compareFilterValues(a, b); ok {
+		return order == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// compareFilterValues orders numbers, strings and timestamps.
+// ok is false if a and b can't be ordered
+func compareFilterValues(a, b any) (order int, ok bool) {
+	switch a := a.(type) {
+	case int64:
+		if b, isInt := b.(int64); isInt {
+			return cmp.Compare(a, b), true
+		}
+	case string:
+		if b, isString := b.(string); isString {
+			return cmp.Compare(a, b), true
+		}
+		return 0, false
+	case time.Time:
+		if b, isTime := b.(time.Time); isTime {
+			return a.Compare(b), true
+		}
+		return 0, false
+	}
+
+	if isNumber(a) && isNumber(b) {
+		return cmp.Compare(toFloat(a), toFloat(b)), true
+	}
+
+	return 0, false
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int64, float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,121 @@
+package firestore
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPostConditionMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data := map[string]any{
+		"name":      "Jane Smith",
+		"email":     "jane@example.com",
+		"age":       int64(42),
+		"score":     7.5,
+		"tags":      []any{"admin", int64(3)},
+		"createdAt": createdAt,
+		"author":    &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/users/abc"},
+		"profile":   map[string]any{"avatar": nil},
+	}
+
+	fixtures := []struct {
+		condition PostCondition
+		expected  bool
+	}{
+		{condition: PostCondition{Key: "name", Operator: PostEq, Value: NewStringValue("Jane Smith")}, expected: true},
+		{condition: PostCondition{Key: "name", Lower: true, Operator: PostContains, Value: NewStringValue("smith")}, expected: true},
+		{condition: PostCondition{Key: "name", Operator: PostContains, Value: NewStringValue("smith")}, expected: false},
+		{condition: PostCondition{Key: "name", Operator: PostStartsWith, Value: NewStringValue("Jane")}, expected: true},
+		{condition: PostCondition{Key: "name", Operator: PostEndsWith, Value: NewStringValue("Doe")}, expected: false},
+		{condition: PostCondition{Key: "email", Operator: PostMatches, Pattern: regexp.MustCompile(`@example\.com$`)}, expected: true},
+		{condition: PostCondition{Key: "email", Operator: PostNotMatches, Pattern: regexp.MustCompile(`@example\.com$`)}, expected: false},
+		{condition: PostCondition{Key: "age", Operator: PostMatches, Pattern: regexp.MustCompile(`4`)}, expected: false},
+		{condition: PostCondition{Key: "age", Operator: PostEq, Value: NewIntValue(42)}, expected: true},
+		{condition: PostCondition{Key: "age", Operator: PostGte, Value: NewFloatValue(41.5)}, expected: true},
+		{condition: PostCondition{Key: "age", Operator: PostLt, Value: NewIntValue(18)}, expected: false},
+		{condition: PostCondition{Key: "age", Operator: PostGt, Value: NewStringValue("a")}, expected: false},
+		{condition: PostCondition{Key: "score", Operator: PostIn, Value: ArrayValue{Values: []Value{NewIntValue(1), NewFloatValue(7.5)}}}, expected: true},
+		{condition: PostCondition{Key: "score", Operator: PostNotIn, Value: ArrayValue{Values: []Value{NewFloatValue(7.5)}}}, expected: false},
+		{condition: PostCondition{Key: "tags", Operator: PostContains, Value: NewStringValue("admin")}, expected: true},
+		{condition: PostCondition{Key: "tags", Operator: PostContains, Value: NewIntValue(3)}, expected: true},
+		{condition: PostCondition{Key: "createdAt", Operator: PostGt, Value: NewTimestampValue(createdAt.Add(-time.Hour))}, expected: true},
+		{condition: PostCondition{Key: "author", Operator: PostEq, Value: NewReferenceValue("users/abc")}, expected: true},
+		{condition: PostCondition{Key: "profile.avatar", Operator: PostEq, Value: NewNullValue()}, expected: true},
+		{condition: PostCondition{Key: "missing", Operator: PostNeq, Value: NewIntValue(1)}, expected: false},
+	}
+
+	for _, fixture := range fixtures {
+		assert.Equal(fixture.expected, fixture.condition.Match(data), fixture.condition.String())
+	}
+}
+
+func TestPostFilterMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	data := map[string]any{
+		"status":  "open",
+		"profile": map[string]any{"avatar": nil},
+	}
+
+	assert.True(ExistsFilter{Key: "profile.avatar"}.Match(data))
+	assert.False(ExistsFilter{Key: "profile.name"}.Match(data))
+	assert.True(MissingFilter{Key: "deletedAt"}.Match(data))
+	assert.False(MissingFilter{Key: "status"}.Match(data))
+
+	filter := PostAndFilter{Filters: []PostFilter{
+		PostCondition{Key: "status", Operator: PostEq, Value: NewStringValue("open")},
+		PostOrFilter{Filters: []PostFilter{
+			MissingFilter{Key: "deletedAt"},
+			ExistsFilter{Key: "restoredAt"},
+		}},
+	}}
+	assert.True(filter.Match(data))
+	assert.Equal(`status == "open" && (missing(deletedAt) || exists(restoredAt))`, filter.String())
+	assert.Equal([]KeyPath{"status", "deletedAt", "restoredAt"}, filter.Keys())
+
+	assert.False(MatchAll([]PostFilter{filter, ExistsFilter{Key: "deletedAt"}}, data))
+	assert.True(MatchAll(nil, data))
+}
+
+func TestPostFilterStats(t *testing.T) {
+	assert := assert.New(t)
+
+	stats := PostFilterStats{Scanned: 120, Matched: 3}
+	assert.Equal("client-side filter: 3 of 120 scanned documents matched", stats.String())
+}
+
+func TestQueryClientSetPostFiltersSelect(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	b := NewQueryClient(client, "users").
+		SetSelect([]KeyPath{"name"}).
+		SetPostFilters([]PostFilter{ExistsFilter{Key: "email"}})
+
+	q, err := b.query.Serialize()
+	assert.NoError(err)
+
+	var req firestorepb.RunQueryRequest
+	assert.NoError(proto.Unmarshal(q, &req))
+
+	var fields []string
+	for _, field := range req.GetStructuredQuery().GetSelect().GetFields() {
+		fields = append(fields, field.GetFieldPath())
+	}
+	assert.Equal([]string{"name", "email"}, fields)
+	assert.Equal(PostFilterStats{}, b.PostFilterStats())
+}
@@ -4,109 +4,583 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"cloud.google.com/go/firestore"
-	"cloud.google.com/go/firestore/apiv1/firestorepb"
 )
 
 const (
 	timeoutRunQuery = 30
 )
 
-type QueryClient struct {
-	query firestore.Query
-}
+type (
+	QueryClient struct {
+		client *firestore.Client
+		// path is the collection document ids are relative to.
+		// it is empty for collection groups
+		path  string
+		query firestore.Query
+		// err holds the first error of the builder methods
+		err error
+
+		orderBy     []orderField
+		cursors     []Cursor
+		pageToken   string
+		limit       int
+		limitToLast bool
+		offset      int
+		// inequalities holds the fields of inequality filters. without order
+		// by fields firestore orders by them first
+		inequalities []KeyPath
+		// splits holds one filter set per query if the filters had to be
+		// split into several queries. nil if they fit into b.query
+		splits [][]Filter
+		// selection is kept to load the fields read by post filters and
+		// cursors as well. trimSelection is set if such fields were added
+		selection     []KeyPath
+		hasSelection  bool
+		trimSelection bool
+		postFilters   []PostFilter
+		// stats is shared by all copies of the client
+		stats *PostFilterStats
+	}
+
+	orderField struct {
+		path KeyPath
+		dir  firestore.Direction
+	}
+)
 
 func NewQueryClient(client *firestore.Client, path string) *QueryClient {
 	return &QueryClient{
-		query: client.Collection(path).Query,
+		client: client,
+		path:   path,
+		query:  client.Collection(path).Query,
 	}
 }
 
-func (b *QueryClient) SetWheres(wheres []Where) *QueryClient {
-	for _, where := range wheres {
-		b.applyWhere(where)
+// NewCollectionGroupQueryClient queries all collections with the id collectionID.
+// if parent is set only collections below that document are queried
+func NewCollectionGroupQueryClient(client *firestore.Client, collectionID, parent string) *QueryClient {
+	b := &QueryClient{client: client}
+
+	query, err := collectionGroupQuery(client, collectionID, parent)
+	if err != nil {
+		b.setErr(fmt.Errorf("creating collection group query: %v", err))
 	}
+	b.query = query
 
 	return b
 }
 
-func (b *QueryClient) SetOrderBy(orderBy string, dir firestore.Direction) *QueryClient {
+// SetFilters applies filters. filters with too many values for a single
+// query are split into several queries whose documents are merged
+func (b *QueryClient) SetFilters(filters []Filter) *QueryClient {
+	sets, postFilters, err := splitFilters(filters)
+	if err != nil {
+		b.setErr(fmt.Errorf("applying filters: %v", err))
+		return b
+	}
+	b.SetPostFilters(postFilters)
+
+	for _, field := range inequalityFields(filters) {
+		if !slices.Contains(b.inequalities, field) {
+			b.inequalities = append(b.inequalities, field)
+		}
+	}
+	if b.hasSelection {
+		b.applySelect()
+	}
+
+	if len(sets) > 1 {
+		// binding the filters early reports invalid values right away
+		for _, set := range sets {
+			if _, err := bindFilters(b.client, b.path, set); err != nil {
+				b.setErr(fmt.Errorf("applying filters: %v", err))
+				return b
+			}
+		}
+
+		b.splits = sets
+		return b
+	}
+
+	query, err := applyFilters(b.client, b.path, b.query, sets[0])
+	if err != nil {
+		b.setErr(fmt.Errorf("applying filters: %v", err))
+		return b
+	}
+
+	b.query = query
+
+	return b
+}
+
+func (b *QueryClient) SetOrderBy(orderBy KeyPath, dir firestore.Direction) *QueryClient {
 	if orderBy == "" {
 		return b
 	}
 
-	b.query = b.query.OrderBy(orderBy, dir)
+	b.query = b.query.OrderByPath(orderBy.FieldPath(), dir)
+	b.orderBy = append(b.orderBy, orderField{path: orderBy, dir: dir})
+	if b.hasSelection {
+		b.applySelect()
+	}
+
+	return b
+}
+
+// SetPostFilters checks every loaded document against filters on the
+// client. a limit counts the matching documents then
+func (b *QueryClient) SetPostFilters(filters []PostFilter) *QueryClient {
+	if len(filters) == 0 {
+		return b
+	}
+
+	b.postFilters = append(b.postFilters, filters...)
+	if b.stats == nil {
+		b.stats = &PostFilterStats{}
+	}
+	if b.hasSelection {
+		b.applySelect()
+	}
 
 	return b
 }
 
+// HasPostFilters reports whether documents are filtered on the client
+func (b QueryClient) HasPostFilters() bool {
+	return len(b.postFilters) > 0
+}
+
+// PostFilterStats returns how many documents the post filters scanned and matched
+func (b QueryClient) PostFilterStats() PostFilterStats {
+	if b.stats == nil {
+		return PostFilterStats{}
+	}
+
+	return *b.stats
+}
+
+// SetSelect only returns the fields at paths.
+// without paths only the document ids are returned
+func (b *QueryClient) SetSelect(paths []KeyPath) *QueryClient {
+	b.selection = paths
+	b.hasSelection = true
+	b.applySelect()
+
+	return b
+}
+
+// applySelect selects the selection, the fields read by the post filters and
+// the order fields. continuing after a document needs its order field values
+func (b *QueryClient) applySelect() {
+	paths := slices.Clone(b.selection)
+	b.trimSelection = false
+
+	keys := postFilterKeys(b.postFilters)
+	for _, field := range b.cursorFields() {
+		if field != DocumentID && !slices.Contains(keys, field) {
+			keys = append(keys, field)
+		}
+	}
+
+	for _, key := range keys {
+		if !slices.Contains(paths, key) {
+			paths = append(paths, key)
+			b.trimSelection = true
+		}
+	}
+
+	fieldPaths := make([]firestore.FieldPath, len(paths))
+	for i, path := range paths {
+		fieldPaths[i] = path.FieldPath()
+	}
+
+	b.query = b.query.SelectPaths(fieldPaths...)
+}
+
+// selectAlso adds paths to the projection of a query with a selection.
+// grouping and aggregating on the client read fields which aren't selected
+func (b *QueryClient) selectAlso(paths []KeyPath) {
+	if !b.hasSelection {
+		return
+	}
+
+	selection := slices.Clone(b.selection)
+	for _, path := range paths {
+		if !slices.Contains(selection, path) {
+			selection = append(selection, path)
+		}
+	}
+
+	b.selection = selection
+	b.applySelect()
+}
+
 func (b *QueryClient) SetLimit(limit int) *QueryClient {
 	if limit <= 0 {
 		return b
 	}
 
 	b.query = b.query.Limit(limit)
+	b.limit = limit
+	b.limitToLast = false
 
 	return b
 }
 
-func (b *QueryClient) applyWhere(where Where) {
-	b.query = b.query.Where(string(where.Key), where.Operator.String(), where.Value.Value())
+// SetLimitToLast returns the last limit documents. requires an order by field
+func (b *QueryClient) SetLimitToLast(limit int) *QueryClient {
+	if limit <= 0 {
+		return b
+	}
+
+	b.query = b.query.LimitToLast(limit)
+	b.limit = limit
+	b.limitToLast = true
+
+	return b
 }
 
-func (b QueryClient) GetDocs() ([]*FirestoreDoc, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
-	defer cancel()
+func (b *QueryClient) SetOffset(offset int) *QueryClient {
+	if offset <= 0 {
+		return b
+	}
 
-	iter := b.query.Documents(ctx)
-	defer iter.Stop()
+	b.query = b.query.Offset(offset)
+	b.offset = offset
 
-	docs, err := iter.GetAll()
-	if errors.Is(err, context.Canceled) {
-		return nil, fmt.Errorf("getting documents timed out")
+	return b
+}
+
+// SetCursor adds a cursor. document cursors are loaded when the query runs
+func (b *QueryClient) SetCursor(cursor Cursor) *QueryClient {
+	b.cursors = append(b.cursors, cursor)
+
+	return b
+}
+
+// SetPageToken continues after the last document of the page that returned token
+func (b *QueryClient) SetPageToken(token string) *QueryClient {
+	b.pageToken = token
+
+	return b
+}
+
+func (b *QueryClient) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// orders returns the order of the query results. without order by fields
+// firestore orders by the inequality fields in lexicographic order, like the
+// sdk does when creating cursors from documents. the document id is added in
+// the direction of the last field to tell apart documents with the same values
+func (b QueryClient) orders() []orderField {
+	orders := slices.Clone(b.orderBy)
+	if len(orders) == 0 {
+		fields := slices.Clone(b.inequalities)
+		slices.SortFunc(fields, func(x, y KeyPath) int {
+			return slices.Compare(x.Segments(), y.Segments())
+		})
+
+		for _, field := range fields {
+			orders = append(orders, orderField{path: field, dir: firestore.Asc})
+		}
+	}
+
+	hasDocumentID := slices.ContainsFunc(orders, func(o orderField) bool {
+		return o.path == DocumentID
+	})
+	if !hasDocumentID {
+		dir := firestore.Asc
+		if len(orders) > 0 {
+			dir = orders[len(orders)-1].dir
+		}
+		orders = append(orders, orderField{path: DocumentID, dir: dir})
 	}
+
+	return orders
+}
+
+// cursorFields returns the fields of the page token cursor
+func (b QueryClient) cursorFields() []KeyPath {
+	orders := b.orders()
+
+	fields := make([]KeyPath, len(orders))
+	for i, o := range orders {
+		fields[i] = o.path
+	}
+
+	return fields
+}
+
+// build applies the cursors to the query. it continues after the document
+// last if set and after the page token otherwise
+func (b QueryClient) build(ctx context.Context, last *firestore.DocumentSnapshot) (firestore.Query, error) {
+	if b.err != nil {
+		return b.query, b.err
+	}
+
+	return b.applyCursors(ctx, b.query, last)
+}
+
+// buildSplits returns one query per filter set of a split query. the offset
+// is applied to the merged documents, so it is removed from every query.
+// every query is ordered explicitly by orders, so its pages can continue after
+// the cursor values of a document
+func (b QueryClient) buildSplits(ctx context.Context) ([]firestore.Query, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	after, err := b.pageTokenValues()
 	if err != nil {
 		return nil, err
 	}
 
+	queries := make([]firestore.Query, len(b.splits))
+	for i, set := range b.splits {
+		q, err := applyFilters(b.client, b.path, b.query.Offset(0), set)
+		if err != nil {
+			return nil, fmt.Errorf("applying filters: %v", err)
+		}
+		if b.limitToLast {
+			q = q.LimitToLast(b.limit + b.offset)
+		}
+
+		q, err = b.applyUserCursors(ctx, b.orderImplicitly(q))
+		if err != nil {
+			return nil, err
+		}
+		if after != nil {
+			q = q.StartAfter(after...)
+		}
+		queries[i] = q
+	}
+
+	return queries, nil
+}
+
+// applyCursors applies the cursors to q and continues after the document
+// last or the page token
+func (b QueryClient) applyCursors(ctx context.Context, q firestore.Query, last *firestore.DocumentSnapshot) (firestore.Query, error) {
+	q, err := b.applyUserCursors(ctx, q)
+	if err != nil {
+		return q, err
+	}
+
+	if last != nil {
+		values, err := cursorValues(last, b.cursorFields())
+		if err != nil {
+			return q, err
+		}
+		return b.startAfter(q, values), nil
+	}
+
+	values, err := b.pageTokenValues()
+	if err != nil || values == nil {
+		return q, err
+	}
+
+	return b.startAfter(q, values), nil
+}
+
+func (b QueryClient) applyUserCursors(ctx context.Context, q firestore.Query) (firestore.Query, error) {
+	for _, cursor := range b.cursors {
+		var err error
+		q, err = cursor.apply(ctx, b.client, q)
+		if err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+// pageTokenValues returns the cursor values of the page token. it returns
+// nil without page token
+func (b QueryClient) pageTokenValues() ([]any, error) {
+	if b.pageToken == "" {
+		return nil, nil
+	}
+
+	values, err := decodePageToken(b.client, b.pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != len(b.cursorFields()) {
+		return nil, fmt.Errorf("%v: it doesn't match the order by fields", ErrInvalidPageToken)
+	}
+
+	return values, nil
+}
+
+// startAfter continues q after the cursor values
+func (b QueryClient) startAfter(q firestore.Query, values []any) firestore.Query {
+	return b.orderImplicitly(q).StartAfter(values...)
+}
+
+// orderImplicitly orders q explicitly by the implicit order fields. the sdk
+// only knows about the implicit order of single inequality filters, so cursor
+// values wouldn't match the order of composite filters otherwise
+func (b QueryClient) orderImplicitly(q firestore.Query) firestore.Query {
+	for _, o := range b.orders()[len(b.orderBy):] {
+		q = q.OrderByPath(o.path.FieldPath(), o.dir)
+	}
+
+	return q
+}
+
+func (b QueryClient) GetDocs(options DocOptions) ([]*FirestoreDoc, error) {
+	docs, _, err := b.GetPage(options)
+	return docs, err
+}
+
+// GetPage returns the documents and a token for the next page. the token
+// is only set if a limit is set and there might be more documents
+func (b QueryClient) GetPage(options DocOptions) ([]*FirestoreDoc, string, error) {
+	// the limit counts matching documents, so pages are loaded until it is reached
+	if len(b.postFilters) > 0 && !b.limitToLast {
+		out := make([]*FirestoreDoc, 0)
+		token, err := b.Stream(options, func(doc *FirestoreDoc) error {
+			out = append(out, doc)
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		return out, token, nil
+	}
+
+	var docs []*firestore.DocumentSnapshot
+	var err error
+	if b.splits != nil {
+		docs, err = b.splitSnapshots()
+	} else {
+		docs, err = b.getSnapshots()
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
 	var out []*FirestoreDoc
+	var last *firestore.DocumentSnapshot
 	for _, doc := range docs {
-		if doc == nil || !doc.Exists() {
+		if doc == nil || !doc.Exists() || !b.matches(doc) {
 			continue
 		}
 
-		out = append(out, NewFirestoreDoc(doc.Data()))
+		out = append(out, b.newDoc(doc, options))
+		last = doc
 	}
 
 	if out == nil {
-		return make([]*FirestoreDoc, 0), nil
+		return make([]*FirestoreDoc, 0), "", nil
+	}
+
+	if b.limit <= 0 || b.limitToLast || len(out) < b.limit {
+		return out, "", nil
 	}
-	return out, nil
+
+	token, err := b.nextPageToken(last)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return out, token, nil
 }
 
-func (b QueryClient) GetCount() (int, error) {
+func (b QueryClient) getSnapshots() ([]*firestore.DocumentSnapshot, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
 	defer cancel()
 
-	aggr := b.query.NewAggregationQuery().WithCount("count")
-	res, err := aggr.Get(ctx)
+	query, err := b.build(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	docs, err := iter.GetAll()
 	if errors.Is(err, context.Canceled) {
-		return 0, fmt.Errorf("getting documents count timed out")
+		return nil, fmt.Errorf("getting documents timed out")
 	}
+
+	return docs, err
+}
+
+// nextPageToken returns the token to continue after last
+func (b QueryClient) nextPageToken(last *firestore.DocumentSnapshot) (string, error) {
+	values, err := cursorValues(last, b.cursorFields())
+	if err != nil {
+		return "", fmt.Errorf("creating page token: %v", err)
+	}
+
+	token, err := encodePageToken(values)
+	if err != nil {
+		return "", fmt.Errorf("creating page token: %v", err)
+	}
+
+	return token, nil
+}
+
+func (b QueryClient) GetCount() (int, error) {
+	if b.countsOnClient() {
+		var count int
+		_, err := b.streamSnapshots(func(*firestore.DocumentSnapshot) error {
+			count++
+			return nil
+		})
+		return count, err
+	}
+
+	res, err := b.GetAggregations([]Aggregation{{Kind: AggregateCount}})
 	if err != nil {
 		return 0, err
 	}
 
-	value, ok := res["count"]
+	count, ok := res[AggregateCount.String()].(int64)
 	if !ok {
-		return 0, fmt.Errorf("missing 'count' key in response")
+		return 0, fmt.Errorf("converting to int. got %T", res[AggregateCount.String()])
+	}
+
+	return int(count), nil
+}
+
+// countsOnClient reports whether counts and aggregations have to be computed
+// on the client, since firestore doesn't know about post filters and split queries
+func (b QueryClient) countsOnClient() bool {
+	return len(b.postFilters) > 0 || b.splits != nil
+}
+
+// matches checks snapshot against the post filters and counts it
+func (b QueryClient) matches(snapshot *firestore.DocumentSnapshot) bool {
+	if len(b.postFilters) == 0 {
+		return true
+	}
+
+	b.stats.Scanned++
+	if !MatchAll(b.postFilters, snapshot.Data()) {
+		return false
 	}
 
-	if v, ok := value.(*firestorepb.Value); ok {
-		return int(v.GetIntegerValue()), nil
+	b.stats.Matched++
+	return true
+}
+
+// newDoc drops the fields that were only loaded for the post filters
+func (b QueryClient) newDoc(snapshot *firestore.DocumentSnapshot, options DocOptions) *FirestoreDoc {
+	doc := newFirestoreDocFromSnapshot(snapshot, options)
+	if b.trimSelection {
+		doc.Value = selectFields(doc.Value, b.selection)
 	}
 
-	return 0, fmt.Errorf("converting to int. got %T", value)
+	return doc
 }
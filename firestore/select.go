@@ -0,0 +1,52 @@
+package firestore
+
+// selectFields returns a copy of value that only contains the fields at paths.
+// nested maps are created as needed, missing fields are skipped
+func selectFields(value map[string]any, paths []KeyPath) map[string]any {
+	out := make(map[string]any)
+
+	for _, path := range paths {
+		if path == DocumentID {
+			continue
+		}
+
+		v, found := lookupField(value, path.Segments())
+		if !found {
+			continue
+		}
+
+		setField(out, path.Segments(), v)
+	}
+
+	return out
+}
+
+func lookupField(value map[string]any, segments []string) (any, bool) {
+	var current any = value
+	for _, segment := range segments {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = m[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func setField(value map[string]any, segments []string, v any) {
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := value[segment].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			value[segment] = next
+		}
+		value = next
+	}
+
+	value[segments[len(segments)-1]] = v
+}
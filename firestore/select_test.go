@@ -0,0 +1,184 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSelectFields(t *testing.T) {
+	assert := assert.New(t)
+
+	value := map[string]any{
+		"name": "foo",
+		"age":  42,
+		"address": map[string]any{
+			"city": "Berlin",
+			"zip":  "10115",
+		},
+		"x-id": "abc",
+	}
+
+	fixtures := []struct {
+		paths []KeyPath
+
+		expected map[string]any
+	}{
+		{paths: []KeyPath{}, expected: map[string]any{}},
+		{paths: []KeyPath{"name"}, expected: map[string]any{"name": "foo"}},
+		{paths: []KeyPath{"name", "address.city"}, expected: map[string]any{
+			"name":    "foo",
+			"address": map[string]any{"city": "Berlin"},
+		}},
+		{paths: []KeyPath{"address"}, expected: map[string]any{
+			"address": map[string]any{"city": "Berlin", "zip": "10115"},
+		}},
+		{paths: []KeyPath{"`x-id`"}, expected: map[string]any{"x-id": "abc"}},
+		{paths: []KeyPath{"missing", "name.first", DocumentID}, expected: map[string]any{}},
+	}
+
+	for _, fixture := range fixtures {
+		assert.Equal(fixture.expected, selectFields(value, fixture.paths))
+	}
+
+	// the input stays untouched
+	assert.Len(value["address"], 2)
+}
+
+func TestQueryClientSetSelect(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	fixtures := []struct {
+		paths []KeyPath
+
+		expected []string
+	}{
+		{paths: []KeyPath{"name", "address.city"}, expected: []string{"name", "address.city"}},
+		{paths: []KeyPath{"`x-id`"}, expected: []string{"`x-id`"}},
+		{paths: []KeyPath{}, expected: []string{string(DocumentID)}},
+	}
+
+	for _, fixture := range fixtures {
+		b, err := NewQueryClient(client, "users").SetSelect(fixture.paths).query.Serialize()
+		assert.NoError(err)
+
+		var req firestorepb.RunQueryRequest
+		assert.NoError(proto.Unmarshal(b, &req))
+
+		var fields []string
+		for _, field := range req.GetStructuredQuery().GetSelect().GetFields() {
+			fields = append(fields, field.GetFieldPath())
+		}
+		assert.Equal(fixture.expected, fields)
+	}
+}
+
+func TestQueryClientSelectOrderFields(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	fixtures := []struct {
+		client *QueryClient
+
+		expected []string
+		trim     bool
+	}{
+		{
+			client:   NewQueryClient(client, "users").SetSelect([]KeyPath{"name"}),
+			expected: []string{"name"},
+		},
+		{
+			client:   NewQueryClient(client, "users").SetSelect([]KeyPath{"name"}).SetOrderBy("age", firestore.Desc),
+			expected: []string{"name", "age"},
+			trim:     true,
+		},
+		{
+			client:   NewQueryClient(client, "users").SetOrderBy("name", firestore.Asc).SetSelect([]KeyPath{"name"}),
+			expected: []string{"name"},
+		},
+		{
+			client:   NewQueryClient(client, "users").SetSelect([]KeyPath{}).SetFilters([]Filter{Where{Key: "age", Operator: Gt, Value: NewIntValue(5)}}),
+			expected: []string{"age"},
+			trim:     true,
+		},
+		{
+			client:   NewQueryClient(client, "users").SetSelect([]KeyPath{}).SetOrderBy(DocumentID, firestore.Asc),
+			expected: []string{string(DocumentID)},
+		},
+	}
+
+	for i, fixture := range fixtures {
+		b, err := fixture.client.query.Serialize()
+		assert.NoError(err)
+
+		var req firestorepb.RunQueryRequest
+		assert.NoError(proto.Unmarshal(b, &req))
+
+		var fields []string
+		for _, field := range req.GetStructuredQuery().GetSelect().GetFields() {
+			fields = append(fields, field.GetFieldPath())
+		}
+		assert.Equal(fixture.expected, fields, "index %d", i)
+		assert.Equal(fixture.trim, fixture.client.trimSelection, "index %d", i)
+	}
+}
+
+func TestQueryClientSelectGroupFields(t *testing.T) {
+	assert := assert.New(t)
+
+	client, fake := newFakeFirestore(t, fakeDocs(4, func(i int) map[string]*firestorepb.Value {
+		status := "open"
+		if i%2 == 1 {
+			status = "done"
+		}
+		return map[string]*firestorepb.Value{
+			"name":   {ValueType: &firestorepb.Value_StringValue{StringValue: "x"}},
+			"status": {ValueType: &firestorepb.Value_StringValue{StringValue: status}},
+			"amount": fakeInt(i),
+		}
+	}))
+
+	// grouping and summing read fields which aren't selected
+	result, err := NewQueryClient(client, "users").
+		SetSelect([]KeyPath{"name"}).
+		GroupBy(GroupOptions{Keys: []GroupKey{{Field: "status"}}, Sums: []KeyPath{"amount"}})
+	if assert.NoError(err) {
+		assert.Equal([][]any{
+			{"done", int64(2), int64(4)},
+			{"open", int64(2), int64(2)},
+		}, result.Rows)
+	}
+
+	aggregations, err := NewQueryClient(client, "users").
+		SetSelect([]KeyPath{"name"}).
+		SetPostFilters([]PostFilter{PostCondition{Key: "name", Operator: PostEq, Value: NewStringValue("x")}}).
+		GetAggregations([]Aggregation{{Kind: AggregateCount}, {Kind: AggregateSum, Field: "amount"}})
+	if assert.NoError(err) {
+		assert.Equal(map[string]any{"count": int64(4), "sum_amount": int64(6)}, aggregations)
+	}
+
+	for _, q := range fake.Queries() {
+		var fields []string
+		for _, field := range q.GetSelect().GetFields() {
+			fields = append(fields, field.GetFieldPath())
+		}
+		assert.Contains(fields, "name")
+		assert.Contains(fields, "amount")
+	}
+}
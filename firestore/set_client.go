@@ -51,7 +51,9 @@ func (c SetClient) SetMany(data JSONArray, options SetOptions) error {
 	for i, obj := range data.Values {
 		var doc *firestore.DocumentRef
 
-		if id, found := obj["id"]; found {
+		if i < len(data.IDs) && data.IDs[i] != "" {
+			doc = collection.Doc(data.IDs[i])
+		} else if id, found := obj["id"]; found {
 			idStr, ok := id.(string)
 			if !ok {
 				return fmt.Errorf("id must be of type string. got: %T", id)
@@ -64,7 +66,12 @@ func (c SetClient) SetMany(data JSONArray, options SetOptions) error {
 			doc = collection.NewDoc()
 		}
 
-		_, err := writer.Set(doc, obj, setOptions...)
+		value, err := c.resolveValue(obj)
+		if err != nil {
+			return err
+		}
+
+		_, err = writer.Set(doc, value, setOptions...)
 		if errors.Is(err, context.Canceled) {
 			return fmt.Errorf("setting documents timed out")
 		}
@@ -94,7 +101,12 @@ func (c SetClient) Set(data JSONObject, options SetOptions) error {
 		setOptions = append(setOptions, firestore.MergeAll)
 	}
 
-	_, err := c.client.Doc(c.path).Set(ctx, data.Value, setOptions...)
+	value, err := c.resolveValue(data.Value)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Doc(c.path).Set(ctx, value, setOptions...)
 	if errors.Is(err, context.Canceled) {
 		return fmt.Errorf("setting document timed out")
 	}
@@ -108,3 +120,42 @@ func (c SetClient) Set(data JSONObject, options SetOptions) error {
 
 	return nil
 }
+
+// resolveValue replaces placeholders and transform sentinels in decoded
+// input data with the values the firestore sdk expects
+func (c SetClient) resolveValue(value any) (any, error) {
+	switch value := value.(type) {
+	case RefPath:
+		ref := c.client.Doc(string(value))
+		if ref == nil {
+			return nil, fmt.Errorf("invalid document reference %s", value)
+		}
+		return ref, nil
+	case []any:
+		out := make([]any, len(value))
+		for i, v := range value {
+			resolved, err := c.resolveValue(v)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	case map[string]any:
+		if key, arg, ok := transformSentinel(value); ok {
+			return toTransform(key, arg, c.resolveValue)
+		}
+
+		out := make(map[string]any, len(value))
+		for key, v := range value {
+			resolved, err := c.resolveValue(v)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,481 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
+)
+
+// firestore limits queries to 30 disjunctions in disjunctive normal form,
+// e.g. a in [1, 2] && (b == 1 || c == 2) has 4. in and array-contains-any
+// lists are split into chunks so every query stays within that limit. their
+// documents are merged. not-in lists are partly checked on the client
+
+const (
+	// maxDisjunctions is the maximum number of disjunctions of a query
+	maxDisjunctions = 30
+	// maxNotInValues is the maximum number of values of not-in
+	maxNotInValues = 10
+	// maxSplitQueries limits the number of queries a filter is split into
+	maxSplitQueries = 500
+	// maxConcurrentQueries is the number of split queries running at the same time
+	maxConcurrentQueries = 8
+)
+
+var (
+	ErrNotInDocumentID     = fmt.Errorf("%s not-in supports at most %d values", DocumentID, maxNotInValues)
+	ErrNotInDisjunction    = fmt.Errorf("not-in with more than %d values can't be combined with ||", maxNotInValues)
+	ErrTooManySplitQueries = fmt.Errorf("in and array-contains-any lists can be split into at most %d queries", maxSplitQueries)
+	ErrTooManyDisjunctions = fmt.Errorf("filters have more than %d disjunctions, even with in and array-contains-any lists split up", maxDisjunctions)
+)
+
+// splitFilters returns one set of filters per query needed for filters.
+// in and array-contains-any lists are split into chunks if filters have more
+// than maxDisjunctions disjunctions. not-in values beyond maxNotInValues are
+// returned as post filters. the documents of all sets together are the
+// documents of filters
+func splitFilters(filters []Filter) ([][]Filter, []PostFilter, error) {
+	var postFilters []PostFilter
+	limited := make([]Filter, len(filters))
+	for i, filter := range filters {
+		f, post, err := limitNotIn(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		limited[i] = f
+		postFilters = append(postFilters, post...)
+	}
+
+	sizes, err := chunkSizes(limited)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sets, err := expandFilters(limited, chunkLists(sizes, false))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sets, postFilters, nil
+}
+
+// limitNotIn keeps the first maxNotInValues values of not-in conditions.
+// the others are checked by a post filter, which only works if the
+// condition has to match, i.e. it isn't part of an ||
+func limitNotIn(filter Filter) (Filter, []PostFilter, error) {
+	switch filter := filter.(type) {
+	case Where:
+		values, ok := filter.Value.(ArrayValue)
+		if filter.Operator != NotIn || !ok || len(values.Values) <= maxNotInValues {
+			return filter, nil, nil
+		}
+		if filter.Key == DocumentID {
+			return nil, nil, ErrNotInDocumentID
+		}
+
+		post := PostCondition{
+			Key:      filter.Key,
+			Operator: PostNotIn,
+			Value:    ArrayValue{Values: values.Values[maxNotInValues:]},
+		}
+		filter.Value = ArrayValue{Values: values.Values[:maxNotInValues]}
+
+		return filter, []PostFilter{post}, nil
+	case AndFilter:
+		var postFilters []PostFilter
+		filters := make([]Filter, len(filter.Filters))
+		for i, f := range filter.Filters {
+			limited, post, err := limitNotIn(f)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			filters[i] = limited
+			postFilters = append(postFilters, post...)
+		}
+
+		return AndFilter{Filters: filters}, postFilters, nil
+	case OrFilter:
+		if hasOversizedNotIn(filter.Filters) {
+			return nil, nil, ErrNotInDisjunction
+		}
+		return filter, nil, nil
+	default:
+		return filter, nil, nil
+	}
+}
+
+func hasOversizedNotIn(filters []Filter) bool {
+	for _, filter := range filters {
+		switch filter := filter.(type) {
+		case Where:
+			values, ok := filter.Value.(ArrayValue)
+			if filter.Operator == NotIn && ok && len(values.Values) > maxNotInValues {
+				return true
+			}
+		case AndFilter:
+			if hasOversizedNotIn(filter.Filters) {
+				return true
+			}
+		case OrFilter:
+			if hasOversizedNotIn(filter.Filters) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// chunkSizes returns the chunk size of every in and array-contains-any list
+// of filters, in the order expandFilters visits them. the largest chunks are
+// made smaller until the largest possible set has at most maxDisjunctions
+func chunkSizes(filters []Filter) ([]int, error) {
+	var sizes []int
+	_, err := expandFilters(filters, func(w Where, values []Value) []Filter {
+		sizes = append(sizes, min(len(values), maxDisjunctions))
+		return []Filter{w}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		// the first chunks are the largest ones
+		largest, err := expandFilters(filters, chunkLists(sizes, true))
+		if err != nil {
+			return nil, err
+		}
+		if disjunctions(largest[0]) <= maxDisjunctions {
+			return sizes, nil
+		}
+
+		if len(sizes) == 0 {
+			return nil, ErrTooManyDisjunctions
+		}
+
+		i := slices.Index(sizes, slices.Max(sizes))
+		if sizes[i] <= 1 {
+			return nil, ErrTooManyDisjunctions
+		}
+		sizes[i]--
+	}
+}
+
+// chunkLists returns a chunk function for expandFilters which splits the lists
+// into chunks of sizes. with firstOnly only the first chunk of every list is kept
+func chunkLists(sizes []int, firstOnly bool) func(w Where, values []Value) []Filter {
+	next := 0
+	return func(w Where, values []Value) []Filter {
+		size := sizes[next]
+		next++
+
+		var out []Filter
+		for chunk := range slices.Chunk(values, size) {
+			out = append(out, Where{
+				Key:      w.Key,
+				Operator: w.Operator,
+				Value:    ArrayValue{Values: chunk},
+			})
+			if firstOnly {
+				break
+			}
+		}
+		return out
+	}
+}
+
+// disjunctions returns the number of disjunctions of filters in disjunctive
+// normal form. lists of in and array-contains-any count one per value
+func disjunctions(filters []Filter) int {
+	n := 1
+	for _, filter := range filters {
+		// large numbers are capped, as they only have to exceed the limit
+		n = min(n*filterDisjunctions(filter), math.MaxInt32)
+	}
+
+	return n
+}
+
+func filterDisjunctions(filter Filter) int {
+	switch filter := filter.(type) {
+	case Where:
+		if values, ok := disjunctionList(filter); ok {
+			return max(len(values), 1)
+		}
+		return 1
+	case AndFilter:
+		return disjunctions(filter.Filters)
+	case OrFilter:
+		var n int
+		for _, f := range filter.Filters {
+			n = min(n+filterDisjunctions(f), math.MaxInt32)
+		}
+		return n
+	default:
+		return 1
+	}
+}
+
+// disjunctionList returns the values of in and array-contains-any conditions
+func disjunctionList(w Where) ([]Value, bool) {
+	values, ok := w.Value.(ArrayValue)
+	if (w.Operator != In && w.Operator != ArrayContainsAny) || !ok {
+		return nil, false
+	}
+
+	return values.Values, true
+}
+
+// expandFilters returns the product of the expansions of all filters.
+// chunk returns the conditions replacing an in or array-contains-any list.
+// filters only combine conditions with && and ||, so replacing a list
+// with each of its chunks and merging the results matches the same documents
+func expandFilters(filters []Filter, chunk func(w Where, values []Value) []Filter) ([][]Filter, error) {
+	sets := [][]Filter{{}}
+	for _, filter := range filters {
+		expanded, err := expandFilter(filter, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sets)*len(expanded) > maxSplitQueries {
+			return nil, ErrTooManySplitQueries
+		}
+
+		product := make([][]Filter, 0, len(sets)*len(expanded))
+		for _, set := range sets {
+			for _, f := range expanded {
+				product = append(product, append(slices.Clone(set), f))
+			}
+		}
+		sets = product
+	}
+
+	return sets, nil
+}
+
+func expandFilter(filter Filter, chunk func(w Where, values []Value) []Filter) ([]Filter, error) {
+	switch filter := filter.(type) {
+	case Where:
+		if values, ok := disjunctionList(filter); ok {
+			return chunk(filter, values), nil
+		}
+		return []Filter{filter}, nil
+	case AndFilter:
+		sets, err := expandFilters(filter.Filters, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make([]Filter, len(sets))
+		for i, set := range sets {
+			out[i] = AndFilter{Filters: set}
+		}
+		return out, nil
+	case OrFilter:
+		sets, err := expandFilters(filter.Filters, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		out := make([]Filter, len(sets))
+		for i, set := range sets {
+			out[i] = OrFilter{Filters: set}
+		}
+		return out, nil
+	default:
+		return []Filter{filter}, nil
+	}
+}
+
+// loadSplitQueries runs load for all queries with at most maxConcurrentQueries
+// at the same time. documents matched by several queries are only kept once
+func loadSplitQueries(queries []firestore.Query, load func(q firestore.Query) ([]*firestore.DocumentSnapshot, error)) ([]*firestore.DocumentSnapshot, error) {
+	results := make([][]*firestore.DocumentSnapshot, len(queries))
+
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentQueries)
+	for i, q := range queries {
+		g.Go(func() error {
+			snapshots, err := load(q)
+			results[i] = snapshots
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var out []*firestore.DocumentSnapshot
+	for _, snapshots := range results {
+		for _, snapshot := range snapshots {
+			if seen[snapshot.Ref.Path] {
+				continue
+			}
+
+			seen[snapshot.Ref.Path] = true
+			out = append(out, snapshot)
+		}
+	}
+
+	return out, nil
+}
+
+// loadAll loads the documents of q in pages of streamPageSize, each with its
+// own timeout. pages continue after the order values of the last document.
+// it stops after limit documents if limit is greater than 0
+func (b QueryClient) loadAll(q firestore.Query, limit int) ([]*firestore.DocumentSnapshot, error) {
+	var out []*firestore.DocumentSnapshot
+	var last *firestore.DocumentSnapshot
+	for {
+		pageSize := streamPageSize
+		if limit > 0 {
+			pageSize = min(pageSize, limit-len(out))
+		}
+
+		page := q.Limit(pageSize)
+		if last != nil {
+			values, err := cursorValues(last, b.cursorFields())
+			if err != nil {
+				return nil, err
+			}
+			page = page.StartAfter(values...)
+		}
+
+		snapshots, err := loadPage(page)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, snapshots...)
+
+		if len(snapshots) < pageSize || limit > 0 && len(out) >= limit {
+			return out, nil
+		}
+		last = snapshots[len(snapshots)-1]
+	}
+}
+
+func loadPage(q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+
+	var out []*firestore.DocumentSnapshot
+	for {
+		snapshot, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return out, nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("getting documents timed out")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, snapshot)
+	}
+}
+
+// loadLast loads the documents of a limit to last query at once. the sdk
+// can't stream them, as it reverses their order after loading all of them
+func loadLast(q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	snapshots, err := q.Documents(ctx).GetAll()
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("getting documents timed out")
+	}
+
+	return snapshots, err
+}
+
+// splitSnapshots loads the documents of all split queries and merges them in
+// the order of the query. offset and limit are applied to the merged documents
+func (b QueryClient) splitSnapshots() ([]*firestore.DocumentSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	queries, err := b.buildSplits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// every query loads enough documents to fill the merged page.
+	// post filters can drop documents, so all documents are loaded then
+	limit := 0
+	if b.limit > 0 && !b.limitToLast && len(b.postFilters) == 0 {
+		limit = b.limit + b.offset
+	}
+
+	snapshots, err := loadSplitQueries(queries, func(q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+		if b.limitToLast {
+			return loadLast(q)
+		}
+		return b.loadAll(q, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.sortSnapshots(snapshots)
+
+	if b.limitToLast {
+		end := max(len(snapshots)-b.offset, 0)
+		return snapshots[max(end-b.limit, 0):end], nil
+	}
+
+	snapshots = snapshots[min(b.offset, len(snapshots)):]
+	if limit > 0 {
+		snapshots = snapshots[:min(b.limit, len(snapshots))]
+	}
+
+	return snapshots, nil
+}
+
+// sortSnapshots orders snapshots like firestore orders the documents of the
+// query, including the implicit order of inequality fields and document ids
+func (b QueryClient) sortSnapshots(snapshots []*firestore.DocumentSnapshot) {
+	orders := b.orders()
+
+	values := make(map[*firestore.DocumentSnapshot][]any, len(snapshots))
+	for _, snapshot := range snapshots {
+		data := snapshot.Data()
+
+		v := make([]any, len(orders))
+		for i, o := range orders {
+			if o.path == DocumentID {
+				v[i] = snapshot.Ref
+				continue
+			}
+			v[i], _ = lookupField(data, o.path.Segments())
+		}
+		values[snapshot] = v
+	}
+
+	slices.SortStableFunc(snapshots, func(x, y *firestore.DocumentSnapshot) int {
+		for i, o := range orders {
+			c := compareValues(values[x][i], values[y][i])
+			if o.dir == firestore.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
@@ -0,0 +1,293 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func intValues(n int) ArrayValue {
+	values := NewArrayValue()
+	for i := range n {
+		values.Add(NewIntValue(i))
+	}
+
+	return values
+}
+
+func TestSplitFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		filters []Filter
+
+		sets        int
+		postFilters int
+	}{
+		{filters: []Filter{Where{Key: "id", Operator: In, Value: intValues(30)}}, sets: 1},
+		{filters: []Filter{Where{Key: "id", Operator: In, Value: intValues(65)}}, sets: 3},
+		{filters: []Filter{Where{Key: "tags", Operator: ArrayContainsAny, Value: intValues(31)}}, sets: 2},
+		{filters: []Filter{Where{Key: "tags", Operator: ArrayContains, Value: intValues(31)}}, sets: 1},
+		{filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(40)},
+			Where{Key: "b", Operator: In, Value: intValues(70)},
+		}, sets: 96},
+		{filters: []Filter{OrFilter{Filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(40)},
+			Where{Key: "b", Operator: Eq, Value: NewIntValue(1)},
+		}}}, sets: 2},
+		{filters: []Filter{Where{Key: "status", Operator: NotIn, Value: intValues(10)}}, sets: 1},
+		{filters: []Filter{AndFilter{Filters: []Filter{
+			Where{Key: "status", Operator: NotIn, Value: intValues(25)},
+			Where{Key: "id", Operator: In, Value: intValues(45)},
+		}}}, sets: 2, postFilters: 1},
+	}
+
+	for i, fixture := range fixtures {
+		sets, postFilters, err := splitFilters(fixture.filters)
+		if assert.NoError(err, i) {
+			assert.Len(sets, fixture.sets, i)
+			assert.Len(postFilters, fixture.postFilters, i)
+			for _, set := range sets {
+				assert.LessOrEqual(disjunctions(set), maxDisjunctions, i)
+			}
+		}
+	}
+}
+
+func TestSplitFiltersDisjunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		filters []Filter
+
+		sizes []int
+	}{
+		{filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(40)},
+			Where{Key: "b", Operator: In, Value: intValues(70)},
+		}, sizes: []int{5, 6}},
+		{filters: []Filter{OrFilter{Filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(40)},
+			Where{Key: "b", Operator: Eq, Value: NewIntValue(1)},
+		}}}, sizes: []int{29}},
+		{filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(3)},
+			Where{Key: "b", Operator: ArrayContainsAny, Value: intValues(20)},
+		}, sizes: []int{3, 10}},
+		{filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(3)},
+			Where{Key: "b", Operator: In, Value: intValues(10)},
+		}, sizes: []int{3, 10}},
+	}
+
+	for i, fixture := range fixtures {
+		sizes, err := chunkSizes(fixture.filters)
+		if assert.NoError(err, i) {
+			assert.Equal(fixture.sizes, sizes, i)
+		}
+	}
+}
+
+func TestDisjunctions(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		filters []Filter
+
+		disjunctions int
+	}{
+		{filters: []Filter{}, disjunctions: 1},
+		{filters: []Filter{Where{Key: "a", Operator: Eq, Value: NewIntValue(1)}}, disjunctions: 1},
+		{filters: []Filter{Where{Key: "a", Operator: In, Value: intValues(4)}}, disjunctions: 4},
+		{filters: []Filter{Where{Key: "a", Operator: NotIn, Value: intValues(4)}}, disjunctions: 1},
+		{filters: []Filter{
+			Where{Key: "a", Operator: In, Value: intValues(2)},
+			OrFilter{Filters: []Filter{
+				Where{Key: "b", Operator: Eq, Value: NewIntValue(1)},
+				Where{Key: "c", Operator: ArrayContainsAny, Value: intValues(3)},
+			}},
+		}, disjunctions: 8},
+		{filters: []Filter{OrFilter{Filters: []Filter{
+			AndFilter{Filters: []Filter{
+				Where{Key: "a", Operator: In, Value: intValues(2)},
+				Where{Key: "b", Operator: In, Value: intValues(3)},
+			}},
+			Where{Key: "c", Operator: Eq, Value: NewIntValue(1)},
+		}}}, disjunctions: 7},
+	}
+
+	for i, fixture := range fixtures {
+		assert.Equal(fixture.disjunctions, disjunctions(fixture.filters), i)
+	}
+}
+
+func TestSplitFiltersChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	sets, _, err := splitFilters([]Filter{
+		Where{Key: "status", Operator: Eq, Value: NewStringValue("open")},
+		Where{Key: "id", Operator: In, Value: intValues(65)},
+	})
+	assert.NoError(err)
+	if assert.Len(sets, 3) {
+		assert.Equal(Where{Key: "status", Operator: Eq, Value: NewStringValue("open")}, sets[0][0])
+		assert.Len(sets[0][1].(Where).Value.(ArrayValue).Values, 30)
+		assert.Len(sets[1][1].(Where).Value.(ArrayValue).Values, 30)
+		assert.Equal([]any{60, 61, 62, 63, 64}, sets[2][1].(Where).Value.Value())
+	}
+
+	sets, postFilters, err := splitFilters([]Filter{Where{Key: "status", Operator: NotIn, Value: intValues(12)}})
+	assert.NoError(err)
+	if assert.Len(sets, 1) && assert.Len(postFilters, 1) {
+		assert.Equal("status not-in [0, 1, 2, 3, 4, 5, 6, 7, 8, 9]", sets[0][0].String())
+		assert.Equal("status not-in [10, 11]", postFilters[0].String())
+	}
+}
+
+func TestSplitFiltersErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := splitFilters([]Filter{Where{Key: DocumentID, Operator: NotIn, Value: intValues(11)}})
+	assert.ErrorIs(err, ErrNotInDocumentID)
+
+	_, _, err = splitFilters([]Filter{OrFilter{Filters: []Filter{
+		Where{Key: "status", Operator: NotIn, Value: intValues(11)},
+		Where{Key: "archived", Operator: Eq, Value: NewBoolValue(true)},
+	}}})
+	assert.ErrorIs(err, ErrNotInDisjunction)
+
+	_, _, err = splitFilters([]Filter{
+		Where{Key: "a", Operator: In, Value: intValues(30 * 25)},
+		Where{Key: "b", Operator: In, Value: intValues(30 * 25)},
+	})
+	assert.ErrorIs(err, ErrTooManySplitQueries)
+
+	var conditions []Filter
+	for i := range maxDisjunctions + 1 {
+		conditions = append(conditions, Where{Key: "a", Operator: Eq, Value: NewIntValue(i)})
+	}
+	_, _, err = splitFilters([]Filter{OrFilter{Filters: conditions}})
+	assert.ErrorIs(err, ErrTooManyDisjunctions)
+}
+
+func TestQueryClientSplitFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	b := NewQueryClient(client, "users").
+		SetFilters([]Filter{Where{Key: "age", Operator: In, Value: intValues(45)}}).
+		SetLimit(10).
+		SetOffset(5)
+	assert.Len(b.splits, 2)
+	assert.True(b.countsOnClient())
+
+	queries, err := b.buildSplits(context.Background())
+	assert.NoError(err)
+	if !assert.Len(queries, 2) {
+		return
+	}
+
+	for i, n := range []int{30, 15} {
+		s, err := queries[i].Serialize()
+		assert.NoError(err)
+
+		var req firestorepb.RunQueryRequest
+		assert.NoError(proto.Unmarshal(s, &req))
+
+		query := req.GetStructuredQuery()
+		assert.Len(query.GetWhere().GetFieldFilter().GetValue().GetArrayValue().GetValues(), n)
+		// the offset is applied to the merged documents
+		assert.Equal(int32(0), query.GetOffset())
+	}
+}
+
+func TestSplitSnapshotsLimitToLast(t *testing.T) {
+	assert := assert.New(t)
+
+	client, fake := newFakeFirestore(t, fakeDocs(100, func(i int) map[string]*firestorepb.Value {
+		return map[string]*firestorepb.Value{"n": fakeInt(i)}
+	}))
+
+	docs, token, err := NewQueryClient(client, "users").
+		SetFilters([]Filter{Where{Key: "n", Operator: In, Value: intValues(45)}}).
+		SetOrderBy("n", firestore.Asc).
+		SetLimitToLast(5).
+		SetOffset(2).
+		GetPage(DocOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Empty(token)
+
+	var ids []string
+	for _, doc := range docs {
+		ids = append(ids, doc.Meta.ID)
+	}
+	assert.Equal([]string{"d0038", "d0039", "d0040", "d0041", "d0042"}, ids)
+
+	// every query loads the last documents it needs for the merged page
+	queries := fake.Queries()
+	if assert.Len(queries, 2) {
+		for _, q := range queries {
+			assert.Equal(int32(7), q.GetLimit().GetValue())
+		}
+	}
+}
+
+func TestSplitSnapshotsImplicitOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	// age descends, so ordering by age differs from ordering by document name
+	client, fake := newFakeFirestore(t, fakeDocs(2500, func(i int) map[string]*firestorepb.Value {
+		return map[string]*firestorepb.Value{"id": fakeInt(i % 40), "age": fakeInt(2500 - i)}
+	}))
+
+	b := NewQueryClient(client, "users").
+		SetFilters([]Filter{AndFilter{Filters: []Filter{
+			Where{Key: "id", Operator: In, Value: intValues(45)},
+			Where{Key: "age", Operator: Gt, Value: NewIntValue(5)},
+		}}}).
+		SetLimit(1500)
+
+	docs, token, err := b.GetPage(DocOptions{})
+	if !assert.NoError(err) {
+		return
+	}
+
+	// the merged documents are ordered by age, then by document name
+	if assert.Len(docs, 1500) {
+		for i, doc := range docs {
+			assert.Equal(fmt.Sprintf("d%04d", 2494-i), doc.Meta.ID)
+		}
+	}
+
+	values, err := decodePageToken(client, token)
+	if assert.NoError(err) && assert.Len(values, 2) {
+		assert.Equal(int64(2500-995), values[0])
+	}
+
+	// the pages of every query continue after the age of the last document
+	for _, q := range fake.Queries() {
+		orderBy := q.GetOrderBy()
+		if assert.Len(orderBy, 2) {
+			assert.Equal("age", orderBy[0].GetField().GetFieldPath())
+			assert.Equal(string(DocumentID), orderBy[1].GetField().GetFieldPath())
+		}
+		if start := q.GetStartAt(); start != nil {
+			assert.Len(start.GetValues(), 2)
+		}
+	}
+	assert.Len(fake.Queries(), 3)
+}
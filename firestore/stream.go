@@ -0,0 +1,188 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// streamPageSize is the number of documents loaded per request when
+	// streaming. every page gets its own timeout
+	streamPageSize = 1000
+)
+
+var (
+	ErrStreamLimitToLast = errors.New("streaming documents doesn't support limit to last")
+
+	// errLimitReached stops streaming once limit documents were passed on
+	errLimitReached = errors.New("limit reached")
+)
+
+// Stream calls fn for every document as soon as it arrives. documents are
+// loaded in pages which continue after the last document of the previous page,
+// so long exports don't run into a single timeout. like GetPage it returns
+// a token for the next page if the limit is reached
+func (b QueryClient) Stream(options DocOptions, fn func(doc *FirestoreDoc) error) (string, error) {
+	if b.limitToLast {
+		return "", ErrStreamLimitToLast
+	}
+
+	return b.streamSnapshots(func(snapshot *firestore.DocumentSnapshot) error {
+		return fn(b.newDoc(snapshot, options))
+	})
+}
+
+// streamSnapshots passes the matching documents to fn. counting, aggregating
+// and grouping on the client use it as well
+func (b QueryClient) streamSnapshots(fn func(snapshot *firestore.DocumentSnapshot) error) (string, error) {
+	if b.limitToLast {
+		return "", b.streamLastSnapshots(fn)
+	}
+	if b.splits != nil {
+		return b.streamSplitSnapshots(fn)
+	}
+
+	var last *firestore.DocumentSnapshot
+	var count int
+	for {
+		pageSize := streamPageSize
+		// post filters can drop documents, so full pages are loaded then
+		if b.limit > 0 && len(b.postFilters) == 0 {
+			pageSize = min(pageSize, b.limit-count)
+		}
+
+		n, err := b.streamPage(last, pageSize, func(snapshot *firestore.DocumentSnapshot) error {
+			last = snapshot
+			if !b.matches(snapshot) {
+				return nil
+			}
+
+			err := fn(snapshot)
+			if err != nil {
+				return err
+			}
+
+			count++
+			if b.limit > 0 && count >= b.limit {
+				return errLimitReached
+			}
+			return nil
+		})
+		if errors.Is(err, errLimitReached) {
+			return b.nextPageToken(last)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if n < pageSize {
+			return "", nil
+		}
+	}
+}
+
+// streamPage loads up to pageSize documents after the document last.
+// it returns the number of documents passed to fn
+func (b QueryClient) streamPage(last *firestore.DocumentSnapshot, pageSize int, fn func(snapshot *firestore.DocumentSnapshot) error) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	query, err := b.build(ctx, last)
+	if err != nil {
+		return 0, err
+	}
+
+	query = query.Limit(pageSize)
+	if last != nil {
+		// the offset was applied on the first page already
+		query = query.Offset(0)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var n int
+	for {
+		snapshot, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			return n, nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return n, fmt.Errorf("getting documents timed out")
+		}
+		if err != nil {
+			return n, err
+		}
+
+		n++
+		if !snapshot.Exists() {
+			continue
+		}
+
+		err = fn(snapshot)
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// streamSplitSnapshots passes the merged documents of a split query to fn.
+// all queries have to finish before the documents can be ordered
+func (b QueryClient) streamSplitSnapshots(fn func(snapshot *firestore.DocumentSnapshot) error) (string, error) {
+	snapshots, err := b.splitSnapshots()
+	if err != nil {
+		return "", err
+	}
+
+	var count int
+	for _, snapshot := range snapshots {
+		if !snapshot.Exists() || !b.matches(snapshot) {
+			continue
+		}
+
+		err = fn(snapshot)
+		if err != nil {
+			return "", err
+		}
+
+		count++
+		if b.limit > 0 && count >= b.limit {
+			return b.nextPageToken(snapshot)
+		}
+	}
+
+	return "", nil
+}
+
+// streamLastSnapshots passes the documents of a limit to last query to fn.
+// the sdk can't stream them, so they are loaded at once like GetPage does
+func (b QueryClient) streamLastSnapshots(fn func(snapshot *firestore.DocumentSnapshot) error) error {
+	var snapshots []*firestore.DocumentSnapshot
+	var err error
+	if b.splits != nil {
+		snapshots, err = b.splitSnapshots()
+	} else {
+		snapshots, err = b.getSnapshots()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot == nil || !snapshot.Exists() || !b.matches(snapshot) {
+			continue
+		}
+
+		err = fn(snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,550 @@
+package firestore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeFirestore answers RunQuery requests from a fixed set of documents.
+// it supports the filters, orders, cursors, offsets and limits the tests use
+// and records every query it receives. writes are recorded but not applied
+type fakeFirestore struct {
+	firestorepb.UnimplementedFirestoreServer
+
+	docs []*firestorepb.Document
+
+	mu      sync.Mutex
+	queries []*firestorepb.StructuredQuery
+	writes  []*firestorepb.Write
+}
+
+const fakeDocumentsPath = "projects/demo-test/databases/(default)/documents"
+
+// newFakeFirestore starts a fake server with docs and returns a client connected to it
+func newFakeFirestore(t *testing.T, docs []*firestorepb.Document) (*firestore.Client, *fakeFirestore) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeFirestore{docs: docs}
+	server := grpc.NewServer()
+	firestorepb.RegisterFirestoreServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", listener.Addr().String())
+	client, err := NewClient("demo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client, fake
+}
+
+// fakeDocs returns n documents users/d0000, users/d0001, ... with the fields of fields(i)
+func fakeDocs(n int, fields func(i int) map[string]*firestorepb.Value) []*firestorepb.Document {
+	docs := make([]*firestorepb.Document, n)
+	for i := range docs {
+		docs[i] = &firestorepb.Document{
+			Name:       fmt.Sprintf("%s/users/d%04d", fakeDocumentsPath, i),
+			Fields:     fields(i),
+			CreateTime: timestamppb.Now(),
+			UpdateTime: timestamppb.Now(),
+		}
+	}
+
+	return docs
+}
+
+func fakeInt(i int) *firestorepb.Value {
+	return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: int64(i)}}
+}
+
+func (f *fakeFirestore) Queries() []*firestorepb.StructuredQuery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.queries)
+}
+
+// Writes returns the names of the written documents in the order they were written
+func (f *fakeFirestore) Writes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, len(f.writes))
+	for i, w := range f.writes {
+		names[i] = w.GetUpdate().GetName()
+	}
+
+	return names
+}
+
+func (f *fakeFirestore) BatchWrite(_ context.Context, req *firestorepb.BatchWriteRequest) (*firestorepb.BatchWriteResponse, error) {
+	f.mu.Lock()
+	f.writes = append(f.writes, req.GetWrites()...)
+	f.mu.Unlock()
+
+	var res firestorepb.BatchWriteResponse
+	for range req.GetWrites() {
+		res.WriteResults = append(res.WriteResults, &firestorepb.WriteResult{UpdateTime: timestamppb.Now()})
+		res.Status = append(res.Status, status.New(codes.OK, "").Proto())
+	}
+
+	return &res, nil
+}
+
+func (f *fakeFirestore) RunQuery(req *firestorepb.RunQueryRequest, stream firestorepb.Firestore_RunQueryServer) error {
+	q := req.GetStructuredQuery()
+
+	f.mu.Lock()
+	f.queries = append(f.queries, proto.Clone(q).(*firestorepb.StructuredQuery))
+	f.mu.Unlock()
+
+	orders := fakeOrders(q)
+	var docs []*firestorepb.Document
+	for _, doc := range f.docs {
+		if q.GetWhere() != nil && !fakeMatch(q.GetWhere(), doc) {
+			continue
+		}
+		if start := q.GetStartAt(); start != nil {
+			c := fakeCompareCursor(doc, orders, start.GetValues())
+			if c < 0 || c == 0 && !start.GetBefore() {
+				continue
+			}
+		}
+		if end := q.GetEndAt(); end != nil {
+			c := fakeCompareCursor(doc, orders, end.GetValues())
+			if c > 0 || c == 0 && end.GetBefore() {
+				continue
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	slices.SortStableFunc(docs, func(x, y *firestorepb.Document) int {
+		for _, o := range orders {
+			c := fakeCompare(fakeField(x, o.GetField().GetFieldPath()), fakeField(y, o.GetField().GetFieldPath()))
+			if o.GetDirection() == firestorepb.StructuredQuery_DESCENDING {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+
+	docs = docs[min(int(q.GetOffset()), len(docs)):]
+	if q.GetLimit() != nil {
+		docs = docs[:min(int(q.GetLimit().GetValue()), len(docs))]
+	}
+
+	for _, doc := range docs {
+		if q.GetSelect() != nil {
+			selected := &firestorepb.Document{Name: doc.Name, CreateTime: doc.CreateTime, UpdateTime: doc.UpdateTime, Fields: map[string]*firestorepb.Value{}}
+			for _, field := range q.GetSelect().GetFields() {
+				if v, found := doc.Fields[field.GetFieldPath()]; found {
+					selected.Fields[field.GetFieldPath()] = v
+				}
+			}
+			doc = selected
+		}
+
+		err := stream.Send(&firestorepb.RunQueryResponse{Document: doc, ReadTime: timestamppb.Now()})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fakeOrders returns the order firestore applies. without order by fields it
+// orders by the inequality fields. the document name is always ordered last
+func fakeOrders(q *firestorepb.StructuredQuery) []*firestorepb.StructuredQuery_Order {
+	orders := slices.Clone(q.GetOrderBy())
+	if len(orders) == 0 {
+		var fields []string
+		fakeInequalities(q.GetWhere(), &fields)
+		slices.Sort(fields)
+		for _, field := range slices.Compact(fields) {
+			orders = append(orders, fakeOrder(field, firestorepb.StructuredQuery_ASCENDING))
+		}
+	}
+
+	hasName := slices.ContainsFunc(orders, func(o *firestorepb.StructuredQuery_Order) bool {
+		return o.GetField().GetFieldPath() == string(DocumentID)
+	})
+	if !hasName {
+		dir := firestorepb.StructuredQuery_ASCENDING
+		if len(orders) > 0 {
+			dir = orders[len(orders)-1].GetDirection()
+		}
+		orders = append(orders, fakeOrder(string(DocumentID), dir))
+	}
+
+	return orders
+}
+
+func fakeOrder(field string, dir firestorepb.StructuredQuery_Direction) *firestorepb.StructuredQuery_Order {
+	return &firestorepb.StructuredQuery_Order{
+		Field:     &firestorepb.StructuredQuery_FieldReference{FieldPath: field},
+		Direction: dir,
+	}
+}
+
+func fakeInequalities(filter *firestorepb.StructuredQuery_Filter, fields *[]string) {
+	if composite := filter.GetCompositeFilter(); composite != nil {
+		for _, f := range composite.GetFilters() {
+			fakeInequalities(f, fields)
+		}
+		return
+	}
+
+	switch filter.GetFieldFilter().GetOp() {
+	case firestorepb.StructuredQuery_FieldFilter_LESS_THAN,
+		firestorepb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL,
+		firestorepb.StructuredQuery_FieldFilter_GREATER_THAN,
+		firestorepb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL,
+		firestorepb.StructuredQuery_FieldFilter_NOT_EQUAL,
+		firestorepb.StructuredQuery_FieldFilter_NOT_IN:
+		*fields = append(*fields, filter.GetFieldFilter().GetField().GetFieldPath())
+	}
+}
+
+func fakeMatch(filter *firestorepb.StructuredQuery_Filter, doc *firestorepb.Document) bool {
+	if composite := filter.GetCompositeFilter(); composite != nil {
+		for _, f := range composite.GetFilters() {
+			matched := fakeMatch(f, doc)
+			if composite.GetOp() == firestorepb.StructuredQuery_CompositeFilter_OR && matched {
+				return true
+			}
+			if composite.GetOp() == firestorepb.StructuredQuery_CompositeFilter_AND && !matched {
+				return false
+			}
+		}
+		return composite.GetOp() == firestorepb.StructuredQuery_CompositeFilter_AND
+	}
+
+	f := filter.GetFieldFilter()
+	field := fakeField(doc, f.GetField().GetFieldPath())
+	if field == nil {
+		return false
+	}
+
+	c := fakeCompare(field, f.GetValue())
+	switch f.GetOp() {
+	case firestorepb.StructuredQuery_FieldFilter_EQUAL:
+		return c == 0
+	case firestorepb.StructuredQuery_FieldFilter_NOT_EQUAL:
+		return c != 0
+	case firestorepb.StructuredQuery_FieldFilter_LESS_THAN:
+		return c < 0
+	case firestorepb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL:
+		return c <= 0
+	case firestorepb.StructuredQuery_FieldFilter_GREATER_THAN:
+		return c > 0
+	case firestorepb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL:
+		return c >= 0
+	case firestorepb.StructuredQuery_FieldFilter_IN:
+		return slices.ContainsFunc(f.GetValue().GetArrayValue().GetValues(), func(v *firestorepb.Value) bool {
+			return fakeCompare(field, v) == 0
+		})
+	case firestorepb.StructuredQuery_FieldFilter_NOT_IN:
+		return !slices.ContainsFunc(f.GetValue().GetArrayValue().GetValues(), func(v *firestorepb.Value) bool {
+			return fakeCompare(field, v) == 0
+		})
+	default:
+		return false
+	}
+}
+
+func fakeField(doc *firestorepb.Document, path string) *firestorepb.Value {
+	if path == string(DocumentID) {
+		return &firestorepb.Value{ValueType: &firestorepb.Value_ReferenceValue{ReferenceValue: doc.GetName()}}
+	}
+
+	return doc.GetFields()[path]
+}
+
+// fakeCompareCursor compares the order values of doc with the cursor values
+func fakeCompareCursor(doc *firestorepb.Document, orders []*firestorepb.StructuredQuery_Order, values []*firestorepb.Value) int {
+	for i, v := range values {
+		c := fakeCompare(fakeField(doc, orders[i].GetField().GetFieldPath()), v)
+		if orders[i].GetDirection() == firestorepb.StructuredQuery_DESCENDING {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// fakeCompare orders missing values, integers, strings and references
+func fakeCompare(a, b *firestorepb.Value) int {
+	rank := func(v *firestorepb.Value) int {
+		switch v.GetValueType().(type) {
+		case *firestorepb.Value_IntegerValue:
+			return 1
+		case *firestorepb.Value_StringValue:
+			return 2
+		case *firestorepb.Value_ReferenceValue:
+			return 3
+		default:
+			return 0
+		}
+	}
+
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+
+	switch a.GetValueType().(type) {
+	case *firestorepb.Value_IntegerValue:
+		return cmp.Compare(a.GetIntegerValue(), b.GetIntegerValue())
+	case *firestorepb.Value_StringValue:
+		return strings.Compare(a.GetStringValue(), b.GetStringValue())
+	case *firestorepb.Value_ReferenceValue:
+		return strings.Compare(a.GetReferenceValue(), b.GetReferenceValue())
+	default:
+		return 0
+	}
+}
+
+func TestStreamLimitToLast(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	client, err := NewClient("demo-test")
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	_, err = NewQueryClient(client, "users").
+		SetOrderBy("age", firestore.Asc).
+		SetLimitToLast(10).
+		Stream(DocOptions{}, func(doc *FirestoreDoc) error {
+			return nil
+		})
+	assert.ErrorIs(err, ErrStreamLimitToLast)
+}
+
+func TestStreamSnapshotsLimitToLast(t *testing.T) {
+	assert := assert.New(t)
+
+	client, _ := newFakeFirestore(t, fakeDocs(100, func(i int) map[string]*firestorepb.Value {
+		return map[string]*firestorepb.Value{"n": fakeInt(i), "id": fakeInt(i % 50)}
+	}))
+
+	// split queries and post filters count, aggregate and group on the client
+	fixtures := []struct {
+		name    string
+		filters []Filter
+		post    []PostFilter
+
+		count int
+		sum   int64
+	}{
+		{
+			name:    "split",
+			filters: []Filter{Where{Key: "id", Operator: In, Value: intValues(45)}},
+			count:   10,
+			sum:     85 + 86 + 87 + 88 + 89 + 90 + 91 + 92 + 93 + 94,
+		},
+		{
+			name: "post filters",
+			post: []PostFilter{PostCondition{Key: "id", Operator: PostLt, Value: NewIntValue(45)}},
+			// the post filters drop 95 to 99 of the last documents
+			count: 5,
+			sum:   90 + 91 + 92 + 93 + 94,
+		},
+	}
+
+	for _, fixture := range fixtures {
+		// the sdk flips the orders of limit to last queries in place,
+		// so every request gets its own query
+		b := func() *QueryClient {
+			return NewQueryClient(client, "users").
+				SetFilters(fixture.filters).
+				SetPostFilters(fixture.post).
+				SetOrderBy("n", firestore.Asc).
+				SetLimitToLast(10)
+		}
+
+		count, err := b().GetCount()
+		if assert.NoError(err, fixture.name) {
+			assert.Equal(fixture.count, count, fixture.name)
+		}
+
+		aggregations, err := b().GetAggregations([]Aggregation{{Kind: AggregateSum, Field: "n"}})
+		if assert.NoError(err, fixture.name) {
+			assert.Equal(fixture.sum, aggregations["sum_n"], fixture.name)
+		}
+
+		result, err := b().GroupBy(GroupOptions{Keys: []GroupKey{{Field: "id"}}})
+		if assert.NoError(err, fixture.name) {
+			assert.Len(result.Rows, fixture.count, fixture.name)
+		}
+	}
+}
+
+func TestStreamSnapshots(t *testing.T) {
+	assert := assert.New(t)
+
+	n := func(i int) map[string]*firestorepb.Value {
+		return map[string]*firestorepb.Value{"n": fakeInt(i)}
+	}
+
+	fixtures := []struct {
+		name        string
+		docs        int
+		limit       int
+		offset      int
+		postFilters []PostFilter
+
+		// pages holds the limit of every request
+		pages    []int32
+		expected int
+		// after is the index of the document the page token continues after. -1 without token
+		after int
+	}{
+		{name: "all documents", docs: 2500, pages: []int32{1000, 1000, 1000}, expected: 2500, after: -1},
+		{name: "limit below page size", docs: 2500, limit: 10, pages: []int32{10}, expected: 10, after: 9},
+		{name: "limit spans pages", docs: 2500, limit: 1500, pages: []int32{1000, 500}, expected: 1500, after: 1499},
+		{name: "limit not reached", docs: 1500, limit: 2000, pages: []int32{1000, 1000}, expected: 1500, after: -1},
+		{name: "offset", docs: 2500, offset: 3, pages: []int32{1000, 1000, 1000}, expected: 2497, after: -1},
+		{
+			name:        "post filters load full pages",
+			docs:        2500,
+			limit:       10,
+			postFilters: []PostFilter{PostCondition{Key: "n", Operator: PostIn, Value: ArrayValue{Values: []Value{NewIntValue(5), NewIntValue(1500)}}}},
+			pages:       []int32{1000, 1000, 1000},
+			expected:    2,
+			after:       -1,
+		},
+		{
+			name:        "post filters count matches for the limit",
+			docs:        2500,
+			limit:       2,
+			postFilters: []PostFilter{PostCondition{Key: "n", Operator: PostIn, Value: ArrayValue{Values: []Value{NewIntValue(5), NewIntValue(1500)}}}},
+			pages:       []int32{1000, 1000},
+			expected:    2,
+			after:       1500,
+		},
+	}
+
+	for _, fixture := range fixtures {
+		client, fake := newFakeFirestore(t, fakeDocs(fixture.docs, n))
+
+		b := NewQueryClient(client, "users").
+			SetPostFilters(fixture.postFilters).
+			SetLimit(fixture.limit).
+			SetOffset(fixture.offset)
+
+		var ids []string
+		token, err := b.streamSnapshots(func(snapshot *firestore.DocumentSnapshot) error {
+			ids = append(ids, snapshot.Ref.ID)
+			return nil
+		})
+		if !assert.NoError(err, fixture.name) {
+			continue
+		}
+		assert.Len(ids, fixture.expected, fixture.name)
+
+		queries := fake.Queries()
+		pages := make([]int32, len(queries))
+		for i, q := range queries {
+			pages[i] = q.GetLimit().GetValue()
+		}
+		assert.Equal(fixture.pages, pages, fixture.name)
+
+		// only the first page skips the offset. the others continue after the last document
+		for i, q := range queries {
+			if i == 0 {
+				assert.Equal(int32(fixture.offset), q.GetOffset(), fixture.name)
+				assert.Nil(q.GetStartAt(), fixture.name)
+				continue
+			}
+
+			assert.Zero(q.GetOffset(), fixture.name)
+			last := fmt.Sprintf("%s/users/d%04d", fakeDocumentsPath, i*1000+fixture.offset-1)
+			if assert.Len(q.GetStartAt().GetValues(), 1, fixture.name) {
+				assert.Equal(last, q.GetStartAt().GetValues()[0].GetReferenceValue(), fixture.name)
+			}
+			assert.False(q.GetStartAt().GetBefore(), fixture.name)
+		}
+
+		if fixture.after < 0 {
+			assert.Empty(token, fixture.name)
+			continue
+		}
+
+		values, err := decodePageToken(client, token)
+		if assert.NoError(err, fixture.name) && assert.Len(values, 1, fixture.name) {
+			assert.Equal(fmt.Sprintf("%s/users/d%04d", fakeDocumentsPath, fixture.after), values[0].(*firestore.DocumentRef).Path, fixture.name)
+		}
+	}
+}
+
+func TestStreamSnapshotsImplicitOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	// n descends, so ordering by n differs from ordering by document name
+	client, fake := newFakeFirestore(t, fakeDocs(1500, func(i int) map[string]*firestorepb.Value {
+		return map[string]*firestorepb.Value{"n": fakeInt(3000 - i), "name": {ValueType: &firestorepb.Value_StringValue{StringValue: "x"}}}
+	}))
+
+	b := NewQueryClient(client, "users").
+		SetFilters([]Filter{AndFilter{Filters: []Filter{
+			Where{Key: "name", Operator: Eq, Value: NewStringValue("x")},
+			Where{Key: "n", Operator: Gt, Value: NewIntValue(0)},
+		}}}).
+		SetSelect([]KeyPath{})
+
+	var ids []string
+	_, err := b.Stream(DocOptions{}, func(doc *FirestoreDoc) error {
+		ids = append(ids, doc.Meta.ID)
+		assert.Empty(doc.Value)
+		return nil
+	})
+	assert.NoError(err)
+
+	// all documents in the order of n, without gaps or duplicates between the pages
+	if assert.Len(ids, 1500) {
+		assert.Equal("d1499", ids[0])
+		assert.Equal("d0000", ids[1499])
+	}
+
+	queries := fake.Queries()
+	if assert.Len(queries, 2) {
+		orderBy := queries[1].GetOrderBy()
+		if assert.Len(orderBy, 2) {
+			assert.Equal("n", orderBy[0].GetField().GetFieldPath())
+			assert.Equal(string(DocumentID), orderBy[1].GetField().GetFieldPath())
+		}
+		assert.Equal(int64(3000-500), queries[1].GetStartAt().GetValues()[0].GetIntegerValue())
+	}
+}
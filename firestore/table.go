@@ -0,0 +1,104 @@
+package firestore
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TableColumns returns the id column followed by the selected fields or the
+// sorted union of the top level fields of docs. with meta the path and
+// timestamps follow the id
+func TableColumns(docs []*FirestoreDoc, selection []KeyPath, withMeta bool) []string {
+	columns := []string{metaColumns[0]}
+	if withMeta {
+		columns = slices.Clone(metaColumns)
+	}
+
+	if selection != nil {
+		for _, path := range selection {
+			columns = append(columns, string(path))
+		}
+		return columns
+	}
+
+	var keys []string
+	for _, doc := range docs {
+		for key := range doc.Value {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		columns = append(columns, string(NewKeyPath(key)))
+	}
+	return columns
+}
+
+// TableRow returns the cells at columns. the leading id and meta columns are
+// taken from the document meta, all other columns are field paths. nested maps
+// and arrays are summarized to fit into a single cell
+func (d *FirestoreDoc) TableRow(columns []string, withMeta bool) []any {
+	row := make([]any, len(columns))
+
+	// the id is the first meta column
+	n := 1
+	if withMeta {
+		n = len(metaColumns)
+	}
+	if d.Meta != nil {
+		for i := range n {
+			row[i] = d.metaValue(metaColumns[i])
+		}
+	}
+
+	for i := n; i < len(columns); i++ {
+		v, found := lookupField(d.Value, KeyPath(columns[i]).Segments())
+		if found {
+			row[i] = summarize(v)
+		}
+	}
+
+	return row
+}
+
+// summarize keeps scalars and lists the keys of maps, e.g. {city, zip}.
+// array items are summarized the same way
+func summarize(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		return fmt.Sprintf("{%s}", strings.Join(keys, ", "))
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = summaryString(summarize(item))
+		}
+
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	default:
+		return cellValue(value)
+	}
+}
+
+func summaryString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return value
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(value)
+	}
+}
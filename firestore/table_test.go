@@ -0,0 +1,51 @@
+package firestore
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestTableColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	docs := []*FirestoreDoc{
+		NewFirestoreDoc(map[string]any{"name": "Alice", "address": map[string]any{"city": "Berlin"}}),
+		NewFirestoreDoc(map[string]any{"name": "Bob", "x-id": int64(1)}),
+	}
+
+	assert.Equal([]string{"id", "address", "name", "`x-id`"}, TableColumns(docs, nil, false))
+	assert.Equal([]string{"id", "name", "address.city"}, TableColumns(docs, []KeyPath{"name", "address.city"}, false))
+	assert.Equal([]string{"id", "path", "createTime", "updateTime", "readTime"}, TableColumns(docs, []KeyPath{}, true))
+}
+
+func TestTableRow(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	doc := NewFirestoreDoc(map[string]any{
+		"name":    "Alice",
+		"address": map[string]any{"zip": "10115", "city": "Berlin"},
+		"tags":    []any{"admin", int64(2), map[string]any{"a": true}, nil},
+		"geo":     &latlng.LatLng{Latitude: 52.52, Longitude: 13.405},
+		"manager": &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/users/bob"},
+	})
+	doc.Meta = &DocMeta{ID: "abc", Path: "users/abc", CreateTime: ts, UpdateTime: ts, ReadTime: ts}
+
+	columns := []string{"id", "address", "geo", "manager", "missing", "name", "tags"}
+	assert.Equal([]any{
+		"abc",
+		"{city, zip}",
+		"52.52, 13.405",
+		"users/bob",
+		nil,
+		"Alice",
+		"[admin, 2, {a}, null]",
+	}, doc.TableRow(columns, false))
+
+	columns = []string{"id", "path", "createTime", "updateTime", "readTime", "address.city"}
+	assert.Equal([]any{"abc", "users/abc", ts, ts, ts, "Berlin"}, doc.TableRow(columns, true))
+}
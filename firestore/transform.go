@@ -0,0 +1,127 @@
+package firestore
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// sentinel keys which can be used in input data to apply
+// server-side transforms instead of writing literal values.
+// e.g. {"updatedAt": {"$serverTimestamp": true}}
+const (
+	transformServerTimestamp = "$serverTimestamp"
+	transformIncrement       = "$increment"
+	transformArrayUnion      = "$arrayUnion"
+	transformArrayRemove     = "$arrayRemove"
+	transformDelete          = "$delete"
+)
+
+func isTransformKey(key string) bool {
+	switch key {
+	case transformServerTimestamp, transformIncrement, transformArrayUnion, transformArrayRemove, transformDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// transformSentinel returns the transform key and its argument if obj
+// is a single-key sentinel object
+func transformSentinel(obj map[string]any) (string, any, bool) {
+	if len(obj) != 1 {
+		return "", nil, false
+	}
+
+	for key, arg := range obj {
+		if isTransformKey(key) {
+			return key, arg, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// checkArrayTransforms returns an error if obj contains a sentinel inside an
+// array. firestore only applies transforms to fields, not to array elements
+func checkArrayTransforms(obj map[string]any) error {
+	for key, value := range obj {
+		if err := checkNestedTransforms(value, false); err != nil {
+			return fmt.Errorf("field %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+func checkNestedTransforms(value any, inArray bool) error {
+	switch value := value.(type) {
+	case []any:
+		for _, v := range value {
+			if err := checkNestedTransforms(v, true); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		if key, arg, ok := transformSentinel(value); ok {
+			if inArray {
+				return fmt.Errorf("%s can't be used inside arrays", key)
+			}
+			// the elements of array transforms are array elements as well
+			return checkNestedTransforms(arg, true)
+		}
+
+		for _, v := range value {
+			if err := checkNestedTransforms(v, inArray); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// toTransform converts a sentinel into the matching firestore transform.
+// resolve is used to convert the elements of array transforms
+func toTransform(key string, arg any, resolve func(any) (any, error)) (any, error) {
+	switch key {
+	case transformServerTimestamp:
+		if arg != true {
+			return nil, fmt.Errorf("%s must be true", key)
+		}
+		return firestore.ServerTimestamp, nil
+	case transformDelete:
+		if arg != true {
+			return nil, fmt.Errorf("%s must be true", key)
+		}
+		return firestore.Delete, nil
+	case transformIncrement:
+		switch arg.(type) {
+		case int64, float64:
+			return firestore.Increment(arg), nil
+		default:
+			return nil, fmt.Errorf("%s must be a number, got %T", key, arg)
+		}
+	case transformArrayUnion, transformArrayRemove:
+		values, ok := arg.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array, got %T", key, arg)
+		}
+
+		resolved := make([]any, len(values))
+		for i, v := range values {
+			r, err := resolve(v)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+
+		if key == transformArrayUnion {
+			return firestore.ArrayUnion(resolved...), nil
+		}
+		return firestore.ArrayRemove(resolved...), nil
+	default:
+		return nil, fmt.Errorf("unknown transform %s", key)
+	}
+}
@@ -0,0 +1,92 @@
+package firestore
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformSentinels(t *testing.T) {
+	assert := assert.New(t)
+
+	identity := func(v any) (any, error) { return v, nil }
+
+	fixtures := []struct {
+		sentinel    map[string]any
+		expected    any
+		shouldError bool
+	}{
+		{sentinel: map[string]any{"$serverTimestamp": true}, expected: firestore.ServerTimestamp},
+		{sentinel: map[string]any{"$delete": true}, expected: firestore.Delete},
+		{sentinel: map[string]any{"$increment": int64(5)}, expected: firestore.Increment(int64(5))},
+		{sentinel: map[string]any{"$increment": 1.5}, expected: firestore.Increment(1.5)},
+		{sentinel: map[string]any{"$arrayUnion": []any{"a", int64(1)}}, expected: firestore.ArrayUnion("a", int64(1))},
+		{sentinel: map[string]any{"$arrayRemove": []any{"a"}}, expected: firestore.ArrayRemove("a")},
+		{sentinel: map[string]any{"$serverTimestamp": false}, shouldError: true},
+		{sentinel: map[string]any{"$increment": "5"}, shouldError: true},
+		{sentinel: map[string]any{"$arrayUnion": "a"}, shouldError: true},
+	}
+
+	for i, fixture := range fixtures {
+		key, arg, ok := transformSentinel(fixture.sentinel)
+		assert.True(ok, "index %d", i)
+
+		v, err := toTransform(key, arg, identity)
+		if fixture.shouldError {
+			assert.Error(err, "index %d", i)
+		} else {
+			assert.NoError(err, "index %d", i)
+			assert.Equal(fixture.expected, v, "index %d", i)
+		}
+	}
+
+	_, _, ok := transformSentinel(map[string]any{"$increment": int64(1), "foo": "bar"})
+	assert.False(ok)
+
+	_, _, ok = transformSentinel(map[string]any{"$unknown": true})
+	assert.False(ok)
+}
+
+func TestTypedTransformSentinels(t *testing.T) {
+	assert := assert.New(t)
+
+	obj, err := DecodeJSONObject(strings.NewReader(`{
+		"updatedAt": {"$serverTimestamp": true},
+		"tags": {"$arrayUnion": [{"stringValue": "a"}, {"integerValue": "1"}]}
+	}`), DecodeOptions{Format: FormatTyped})
+
+	assert.NoError(err)
+	assert.Equal(map[string]any{
+		"updatedAt": map[string]any{"$serverTimestamp": true},
+		"tags":      map[string]any{"$arrayUnion": []any{"a", int64(1)}},
+	}, obj.Value)
+}
+
+func TestArrayTransforms(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		input         string
+		expectedError string
+	}{
+		{input: `{"updatedAt": {"$serverTimestamp": true}, "tags": ["a", "b"]}`},
+		{input: `{"nested": {"at": {"$serverTimestamp": true}}}`},
+		{input: `{"tags": [{"$serverTimestamp": true}]}`, expectedError: "field tags: $serverTimestamp can't be used inside arrays"},
+		{input: `{"items": [{"count": {"$increment": 1}}]}`, expectedError: "field items: $increment can't be used inside arrays"},
+		{input: `{"tags": {"$arrayUnion": [{"$delete": true}]}}`, expectedError: "field tags: $delete can't be used inside arrays"},
+	}
+
+	for i, fixture := range fixtures {
+		_, err := DecodeJSONObject(strings.NewReader(fixture.input), DecodeOptions{})
+		if fixture.expectedError == "" {
+			assert.NoError(err, "index %d", i)
+		} else {
+			assert.EqualError(err, fixture.expectedError, "index %d", i)
+		}
+	}
+
+	_, err := DecodeJSONArray(strings.NewReader(`[{"a": 1}, {"tags": [{"$serverTimestamp": true}]}]`), DecodeOptions{})
+	assert.EqualError(err, "pos 2: field tags: $serverTimestamp can't be used inside arrays")
+}
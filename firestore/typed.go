@@ -0,0 +1,366 @@
+package firestore
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// the typed format follows the json representation of firestore values
+// used by the REST API.
+// see https://firebase.google.com/docs/firestore/reference/rest/v1/Value
+
+const (
+	typedNull      = "nullValue"
+	typedBoolean   = "booleanValue"
+	typedInteger   = "integerValue"
+	typedDouble    = "doubleValue"
+	typedTimestamp = "timestampValue"
+	typedString    = "stringValue"
+	typedBytes     = "bytesValue"
+	typedReference = "referenceValue"
+	typedGeoPoint  = "geoPointValue"
+	typedArray     = "arrayValue"
+	typedMap       = "mapValue"
+)
+
+const (
+	vectorTypeKey   = "__type__"
+	vectorTypeValue = "__vector__"
+	vectorValueKey  = "value"
+)
+
+// RefPath is a document path that is turned into a *firestore.DocumentRef
+// as soon as a client is available, e.g. when writing documents
+type RefPath string
+
+func toTypedFields(fields map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(fields))
+	for key, value := range fields {
+		typed, err := toTypedValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", key, err)
+		}
+
+		out[key] = typed
+	}
+
+	return out, nil
+}
+
+func toTypedValue(value any) (map[string]any, error) {
+	switch value := value.(type) {
+	case nil:
+		return map[string]any{typedNull: nil}, nil
+	case bool:
+		return map[string]any{typedBoolean: value}, nil
+	case int:
+		return map[string]any{typedInteger: strconv.Itoa(value)}, nil
+	case int64:
+		return map[string]any{typedInteger: strconv.FormatInt(value, 10)}, nil
+	case float64:
+		return map[string]any{typedDouble: typedDoubleValue(value)}, nil
+	case string:
+		return map[string]any{typedString: value}, nil
+	case []byte:
+		return map[string]any{typedBytes: base64.StdEncoding.EncodeToString(value)}, nil
+	case time.Time:
+		return map[string]any{typedTimestamp: value.UTC().Format(time.RFC3339Nano)}, nil
+	case *latlng.LatLng:
+		return map[string]any{typedGeoPoint: map[string]any{
+			"latitude":  value.GetLatitude(),
+			"longitude": value.GetLongitude(),
+		}}, nil
+	case *firestore.DocumentRef:
+		return map[string]any{typedReference: value.Path}, nil
+	case RefPath:
+		return map[string]any{typedReference: string(value)}, nil
+	case firestore.Vector64:
+		values := make([]any, len(value))
+		for i, v := range value {
+			values[i] = map[string]any{typedDouble: typedDoubleValue(v)}
+		}
+
+		return map[string]any{typedMap: map[string]any{
+			"fields": map[string]any{
+				vectorTypeKey:  map[string]any{typedString: vectorTypeValue},
+				vectorValueKey: map[string]any{typedArray: map[string]any{"values": values}},
+			},
+		}}, nil
+	case []any:
+		values := make([]any, len(value))
+		for i, v := range value {
+			typed, err := toTypedValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("array index %d: %v", i, err)
+			}
+
+			values[i] = typed
+		}
+
+		return map[string]any{typedArray: map[string]any{"values": values}}, nil
+	case map[string]any:
+		fields, err := toTypedFields(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{typedMap: map[string]any{"fields": fields}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func typedDoubleValue(v float64) any {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	default:
+		return v
+	}
+}
+
+func fromTypedFields(fields map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(fields))
+	for key, value := range fields {
+		v, err := fromTypedValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", key, err)
+		}
+
+		out[key] = v
+	}
+
+	return out, nil
+}
+
+func fromTypedValue(value any) (any, error) {
+	obj, ok := value.(map[string]any)
+	if !ok || len(obj) != 1 {
+		return nil, fmt.Errorf("expected object with a single value type key, got %v", value)
+	}
+
+	for kind, v := range obj {
+		if isTransformKey(kind) {
+			return fromTypedTransform(kind, v)
+		}
+
+		switch kind {
+		case typedNull:
+			return nil, nil
+		case typedBoolean:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a boolean, got %T", kind, v)
+			}
+			return b, nil
+		case typedInteger:
+			return fromTypedInteger(v)
+		case typedDouble:
+			return fromTypedDouble(v)
+		case typedString:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string, got %T", kind, v)
+			}
+			return s, nil
+		case typedBytes:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a base64 string, got %T", kind, v)
+			}
+			return base64.StdEncoding.DecodeString(s)
+		case typedTimestamp:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string, got %T", kind, v)
+			}
+			return time.Parse(time.RFC3339Nano, s)
+		case typedReference:
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string, got %T", kind, v)
+			}
+			return RefPath(relativePath(s)), nil
+		case typedGeoPoint:
+			return fromTypedGeoPoint(v)
+		case typedArray:
+			return fromTypedArray(v)
+		case typedMap:
+			return fromTypedMap(v)
+		default:
+			return nil, fmt.Errorf("unknown value type %s", kind)
+		}
+	}
+
+	return nil, nil
+}
+
+// fromTypedTransform keeps transform sentinels intact. only the elements
+// of array transforms are typed values, all other arguments are plain json
+func fromTypedTransform(key string, arg any) (any, error) {
+	if key != transformArrayUnion && key != transformArrayRemove {
+		return map[string]any{key: arg}, nil
+	}
+
+	values, ok := arg.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array, got %T", key, arg)
+	}
+
+	out := make([]any, len(values))
+	for i, value := range values {
+		v, err := fromTypedValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s index %d: %v", key, i, err)
+		}
+		out[i] = v
+	}
+
+	return map[string]any{key: out}, nil
+}
+
+func fromTypedInteger(v any) (int64, error) {
+	switch v := v.(type) {
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case int64:
+		return v, nil
+	case float64:
+		return 0, fmt.Errorf("%s must be a whole number, got %v", typedInteger, v)
+	default:
+		return 0, fmt.Errorf("%s must be a string, got %T", typedInteger, v)
+	}
+}
+
+func fromTypedDouble(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		switch v {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return 0, fmt.Errorf("invalid %s %q", typedDouble, v)
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %T", typedDouble, v)
+	}
+}
+
+func fromTypedGeoPoint(v any) (*latlng.LatLng, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object, got %T", typedGeoPoint, v)
+	}
+
+	// proto3 json omits zero values, so missing coordinates are 0
+	var point latlng.LatLng
+	if lat, found := obj["latitude"]; found {
+		f, err := fromTypedDouble(lat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude: %v", err)
+		}
+		point.Latitude = f
+	}
+	if lng, found := obj["longitude"]; found {
+		f, err := fromTypedDouble(lng)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude: %v", err)
+		}
+		point.Longitude = f
+	}
+
+	return &point, nil
+}
+
+func fromTypedArray(v any) ([]any, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object, got %T", typedArray, v)
+	}
+
+	rawValues, found := obj["values"]
+	if !found {
+		return []any{}, nil
+	}
+	values, ok := rawValues.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s values must be an array, got %T", typedArray, rawValues)
+	}
+
+	out := make([]any, len(values))
+	for i, value := range values {
+		v, err := fromTypedValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("array index %d: %v", i, err)
+		}
+
+		out[i] = v
+	}
+
+	return out, nil
+}
+
+func fromTypedMap(v any) (any, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object, got %T", typedMap, v)
+	}
+
+	rawFields, found := obj["fields"]
+	if !found {
+		return map[string]any{}, nil
+	}
+	fields, ok := rawFields.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s fields must be an object, got %T", typedMap, rawFields)
+	}
+
+	out, err := fromTypedFields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if out[vectorTypeKey] == vectorTypeValue {
+		return toVector(out[vectorValueKey])
+	}
+
+	return out, nil
+}
+
+func toVector(v any) (firestore.Vector64, error) {
+	values, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("vector value must be an array, got %T", v)
+	}
+
+	vector := make(firestore.Vector64, len(values))
+	for i, value := range values {
+		switch value := value.(type) {
+		case float64:
+			vector[i] = value
+		case int64:
+			vector[i] = float64(value)
+		default:
+			return nil, fmt.Errorf("vector index %d must be a double, got %T", i, value)
+		}
+	}
+
+	return vector, nil
+}
@@ -0,0 +1,117 @@
+package firestore
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestTypedRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 12, 30, 0, 500, time.UTC)
+	value := map[string]any{
+		"null":     nil,
+		"bool":     true,
+		"int":      int64(42),
+		"double":   float64(42),
+		"inf":      math.Inf(-1),
+		"string":   "foo",
+		"bytes":    []byte("foo"),
+		"time":     ts,
+		"geo":      &latlng.LatLng{Latitude: 52.5, Longitude: 13.4},
+		"ref":      &firestore.DocumentRef{Path: "projects/demo/databases/(default)/documents/users/abc", ID: "abc"},
+		"array":    []any{int64(1), math.Inf(1), "bar"},
+		"map":      map[string]any{"nested": int64(1)},
+		"vector":   firestore.Vector64{1, 2.5},
+		"emptyMap": map[string]any{},
+	}
+
+	doc := NewFirestoreDoc(value)
+	doc.options.Format = FormatTyped
+	j, err := json.Marshal(doc)
+	assert.NoError(err)
+
+	decoded, err := DecodeJSONObject(strings.NewReader(string(j)), DecodeOptions{Format: FormatTyped})
+	assert.NoError(err)
+
+	assert.Equal(map[string]any{
+		"null":     nil,
+		"bool":     true,
+		"int":      int64(42),
+		"double":   float64(42),
+		"inf":      math.Inf(-1),
+		"string":   "foo",
+		"bytes":    []byte("foo"),
+		"time":     ts,
+		"geo":      &latlng.LatLng{Latitude: 52.5, Longitude: 13.4},
+		"ref":      RefPath("users/abc"),
+		"array":    []any{int64(1), math.Inf(1), "bar"},
+		"map":      map[string]any{"nested": int64(1)},
+		"vector":   firestore.Vector64{1, 2.5},
+		"emptyMap": map[string]any{},
+	}, decoded.Value)
+}
+
+func TestTypedEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		value    any
+		expected string
+	}{
+		{value: nil, expected: `{"nullValue": null}`},
+		{value: int64(5), expected: `{"integerValue": "5"}`},
+		{value: 5.5, expected: `{"doubleValue": 5.5}`},
+		{value: math.NaN(), expected: `{"doubleValue": "NaN"}`},
+		{value: []any{math.NaN()}, expected: `{"arrayValue": {"values": [{"doubleValue": "NaN"}]}}`},
+		{value: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), expected: `{"timestampValue": "2025-01-01T00:00:00Z"}`},
+		{value: &latlng.LatLng{Latitude: 1, Longitude: 2}, expected: `{"geoPointValue": {"latitude": 1, "longitude": 2}}`},
+	}
+
+	for i, fixture := range fixtures {
+		typed, err := toTypedValue(fixture.value)
+		assert.NoError(err, "index %d", i)
+
+		j, err := json.Marshal(typed)
+		assert.NoError(err, "index %d", i)
+		assert.JSONEq(fixture.expected, string(j), "index %d", i)
+	}
+}
+
+func TestTypedDecodingErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []string{
+		`{"foo": "bar"}`,
+		`{"foo": {"unknownValue": 1}}`,
+		`{"foo": {"integerValue": "1.5"}}`,
+		`{"foo": {"stringValue": "bar", "integerValue": "1"}}`,
+		`{"foo": {"timestampValue": "yesterday"}}`,
+	}
+
+	for i, fixture := range fixtures {
+		_, err := DecodeJSONObject(strings.NewReader(fixture), DecodeOptions{Format: FormatTyped})
+		assert.Error(err, "index %d", i)
+	}
+}
+
+func TestPlainEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	doc := NewFirestoreDoc(map[string]any{
+		"nan":   math.NaN(),
+		"array": []any{math.Inf(1), math.Inf(-1)},
+		"ref":   &firestore.DocumentRef{Path: "projects/demo/databases/(default)/documents/users/abc", ID: "abc"},
+	})
+
+	j, err := json.Marshal(doc)
+	assert.NoError(err)
+	assert.JSONEq(`{"nan": "NaN", "array": ["Infinity", "-Infinity"], "ref": "users/abc"}`, string(j))
+}
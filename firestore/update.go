@@ -0,0 +1,75 @@
+package firestore
+
+import (
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+type (
+	UpdateOperation int
+
+	FieldUpdate struct {
+		Path      KeyPath
+		Operation UpdateOperation
+		// Value is only used by UpdateSet and UpdateIncrement
+		Value Value
+	}
+)
+
+const (
+	UpdateSet             UpdateOperation = iota + 1 // set a.b=<value>
+	UpdateDelete                                     // unset a.b
+	UpdateIncrement                                  // inc a.b=<number>
+	UpdateServerTimestamp                            // server-time a.b
+)
+
+func (o UpdateOperation) String() string {
+	switch o {
+	case UpdateSet:
+		return "set"
+	case UpdateDelete:
+		return "unset"
+	case UpdateIncrement:
+		return "inc"
+	case UpdateServerTimestamp:
+		return "server-time"
+	default:
+		return ""
+	}
+}
+
+func (u FieldUpdate) String() string {
+	switch u.Operation {
+	case UpdateSet, UpdateIncrement:
+		return fmt.Sprintf("%s %s=%s", u.Operation.String(), u.Path, u.Value.String())
+	default:
+		return fmt.Sprintf("%s %s", u.Operation.String(), u.Path)
+	}
+}
+
+func (u FieldUpdate) toFirestore() (firestore.Update, error) {
+	update := firestore.Update{
+		FieldPath: u.Path.FieldPath(),
+	}
+
+	switch u.Operation {
+	case UpdateSet:
+		update.Value = u.Value.Value()
+	case UpdateDelete:
+		update.Value = firestore.Delete
+	case UpdateIncrement:
+		switch u.Value.(type) {
+		case IntValue, FloatValue:
+			update.Value = firestore.Increment(u.Value.Value())
+		default:
+			return update, fmt.Errorf("can't increment %s by non-number %s", u.Path, u.Value.String())
+		}
+	case UpdateServerTimestamp:
+		update.Value = firestore.ServerTimestamp
+	default:
+		return update, fmt.Errorf("invalid update operation for %s", u.Path)
+	}
+
+	return update, nil
+}
@@ -0,0 +1,139 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/steschwa/fq/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	UpdateClient struct {
+		client  *firestore.Client
+		path    string
+		filters []Filter
+	}
+
+	UpdateOptions struct {
+		ShowProgress bool
+		Delay        int
+	}
+)
+
+func NewUpdateClient(client *firestore.Client, path string) *UpdateClient {
+	return &UpdateClient{
+		client: client,
+		path:   path,
+	}
+}
+
+func (c *UpdateClient) SetFilters(filters []Filter) {
+	c.filters = filters
+}
+
+func (c UpdateClient) Exec(fieldUpdates []FieldUpdate, options UpdateOptions) error {
+	if len(fieldUpdates) == 0 {
+		return fmt.Errorf("no field updates")
+	}
+
+	updates := make([]firestore.Update, len(fieldUpdates))
+	for i, fieldUpdate := range fieldUpdates {
+		if fieldUpdate.Value != nil {
+			fieldUpdate.Value = bindValue(c.client, fieldUpdate.Value)
+		}
+
+		update, err := fieldUpdate.toFirestore()
+		if err != nil {
+			return err
+		}
+
+		updates[i] = update
+	}
+
+	if IsCollectionPath(c.path) {
+		return c.updateMany(updates, options)
+	} else if IsDocumentPath(c.path) {
+		return c.updateOne(updates, options)
+	}
+
+	return nil
+}
+
+// updateMany updates all documents matching the filters. filters with too many
+// values are split into several queries and the documents are loaded in pages,
+// so large updates don't run into a single timeout
+func (c UpdateClient) updateMany(updates []firestore.Update, options UpdateOptions) error {
+	query := NewQueryClient(c.client, c.path).
+		SetSelect([]KeyPath{}).
+		SetFilters(c.filters)
+
+	var refs []*firestore.DocumentRef
+	_, err := query.streamSnapshots(func(snapshot *firestore.DocumentSnapshot) error {
+		refs = append(refs, snapshot.Ref)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("loading documents: %v", err)
+	}
+
+	if query.HasPostFilters() {
+		fmt.Fprintln(os.Stderr, query.PostFilterStats().String())
+	}
+
+	if len(refs) == 0 {
+		fmt.Println("no documents to update")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	writer := c.client.BulkWriter(ctx)
+	defer writer.End()
+
+	for i, ref := range refs {
+		_, err = writer.Update(ref, updates)
+		if err != nil {
+			return fmt.Errorf("updating document ref: %v", err)
+		}
+
+		if options.ShowProgress {
+			utils.ClearLine()
+			fmt.Printf("%d/%d", i+1, len(refs))
+		}
+
+		if options.Delay > 0 {
+			time.Sleep(time.Millisecond * time.Duration(options.Delay))
+		}
+	}
+
+	return nil
+}
+
+func (c UpdateClient) updateOne(updates []firestore.Update, options UpdateOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*timeoutRunQuery)
+	defer cancel()
+
+	_, err := c.client.Doc(c.path).Update(ctx, updates)
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("updating document timed out")
+	}
+	if status.Code(err) == codes.NotFound {
+		return ErrDocumentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("updating doc: %v", err)
+	}
+
+	if options.ShowProgress {
+		fmt.Printf("1/1")
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package firestore
+
+import (
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldUpdateToFirestore(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		update      FieldUpdate
+		expected    firestore.Update
+		shouldError bool
+	}{
+		{
+			update:   FieldUpdate{Path: "a.b", Operation: UpdateSet, Value: NewStringValue("foo")},
+			expected: firestore.Update{FieldPath: firestore.FieldPath{"a", "b"}, Value: "foo"},
+		},
+		{
+			update:   FieldUpdate{Path: "a", Operation: UpdateDelete},
+			expected: firestore.Update{FieldPath: firestore.FieldPath{"a"}, Value: firestore.Delete},
+		},
+		{
+			update:   FieldUpdate{Path: "count", Operation: UpdateIncrement, Value: NewIntValue(2)},
+			expected: firestore.Update{FieldPath: firestore.FieldPath{"count"}, Value: firestore.Increment(2)},
+		},
+		{
+			update:   FieldUpdate{Path: "updatedAt", Operation: UpdateServerTimestamp},
+			expected: firestore.Update{FieldPath: firestore.FieldPath{"updatedAt"}, Value: firestore.ServerTimestamp},
+		},
+		{
+			update:      FieldUpdate{Path: "count", Operation: UpdateIncrement, Value: NewStringValue("2")},
+			shouldError: true,
+		},
+	}
+
+	for i, fixture := range fixtures {
+		update, err := fixture.update.toFirestore()
+		if fixture.shouldError {
+			assert.Error(err, "index %d", i)
+		} else {
+			assert.NoError(err, "index %d", i)
+			assert.Equal(fixture.expected, update, "index %d", i)
+		}
+	}
+}
+
+func TestUpdateClientUpdateMany(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		filters []Filter
+
+		expected int
+	}{
+		{filters: nil, expected: 100},
+		{filters: []Filter{Where{Key: "n", Operator: In, Value: intValues(45)}}, expected: 45},
+		{filters: []Filter{Where{Key: "n", Operator: NotIn, Value: intValues(12)}}, expected: 88},
+	}
+
+	for i, fixture := range fixtures {
+		client, fake := newFakeFirestore(t, fakeDocs(100, func(i int) map[string]*firestorepb.Value {
+			return map[string]*firestorepb.Value{"n": fakeInt(i)}
+		}))
+
+		c := NewUpdateClient(client, "users")
+		c.SetFilters(fixture.filters)
+
+		err := c.Exec([]FieldUpdate{{Path: "done", Operation: UpdateSet, Value: NewBoolValue(true)}}, UpdateOptions{})
+		if !assert.NoError(err, i) {
+			continue
+		}
+
+		writes := fake.Writes()
+		assert.Len(writes, fixture.expected, i)
+		slices.Sort(writes)
+		assert.Len(slices.Compact(writes), fixture.expected, i)
+
+		// too many values for a single query are split up or filtered on the client
+		for _, q := range fake.Queries() {
+			values := q.GetWhere().GetFieldFilter().GetValue().GetArrayValue().GetValues()
+			assert.LessOrEqual(len(values), maxDisjunctions, i)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package firestore
 
 import (
+	"encoding/base64"
 	"fmt"
+	"path"
 	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 type (
-	KeyPath string
-
 	Operator int
 
 	Value interface {
@@ -31,6 +35,33 @@ type (
 	ArrayValue struct {
 		Values []Value
 	}
+	TimestampValue struct {
+		value time.Time
+	}
+	// RelativeTimeValue is a timestamp relative to the time it was parsed at,
+	// e.g. now() - 7d
+	RelativeTimeValue struct {
+		now    time.Time
+		offset time.Duration
+	}
+	ReferenceValue struct {
+		path string
+		ref  *firestore.DocumentRef
+	}
+	GeoPointValue struct {
+		latitude  float64
+		longitude float64
+	}
+	BytesValue struct {
+		value []byte
+	}
+
+	// ClientValue is implemented by values which need a firestore
+	// client to produce their sdk value
+	ClientValue interface {
+		Value
+		Bind(client *firestore.Client) Value
+	}
 
 	Where struct {
 		Key      KeyPath
@@ -51,8 +82,12 @@ const (
 	In                                   // in
 	NotIn                                // not-in
 	ArrayContainsAny                     // array-contains-any
+	ArrayContains                        // array-contains
 )
 
+// DocumentID is the special key to filter by document ids or paths
+const DocumentID KeyPath = firestore.DocumentID
+
 var (
 	_ Value = StringValue{}
 	_ Value = IntValue{}
@@ -60,11 +95,13 @@ var (
 	_ Value = BoolValue{}
 	_ Value = ArrayValue{}
 	_ Value = NullValue{}
-)
+	_ Value = TimestampValue{}
+	_ Value = RelativeTimeValue{}
+	_ Value = GeoPointValue{}
+	_ Value = BytesValue{}
 
-func (p KeyPath) Segments() []string {
-	return strings.Split(string(p), ".")
-}
+	_ ClientValue = ReferenceValue{}
+)
 
 func (o Operator) String() string {
 	switch o {
@@ -86,11 +123,22 @@ func (o Operator) String() string {
 		return "not-in"
 	case ArrayContainsAny:
 		return "array-contains-any"
+	case ArrayContains:
+		return "array-contains"
 	default:
 		return ""
 	}
 }
 
+func (o Operator) isInequality() bool {
+	switch o {
+	case Neq, Gt, Lt, Gte, Lte, NotIn:
+		return true
+	default:
+		return false
+	}
+}
+
 func NewStringValue(value string) StringValue {
 	return StringValue{value: value}
 }
@@ -166,6 +214,103 @@ func (v ArrayValue) String() string {
 	return fmt.Sprintf("[%s]", formattedMembers)
 }
 
+func NewTimestampValue(value time.Time) TimestampValue {
+	return TimestampValue{value: value}
+}
+func (v TimestampValue) Value() any {
+	return v.value
+}
+func (v TimestampValue) String() string {
+	return fmt.Sprintf(`timestamp("%s")`, v.value.Format(time.RFC3339Nano))
+}
+
+func NewRelativeTimeValue(now time.Time, offset time.Duration) RelativeTimeValue {
+	return RelativeTimeValue{now: now, offset: offset}
+}
+func (v RelativeTimeValue) Value() any {
+	return v.now.Add(v.offset)
+}
+func (v RelativeTimeValue) String() string {
+	switch {
+	case v.offset > 0:
+		return fmt.Sprintf("now() + %s", v.offset)
+	case v.offset < 0:
+		return fmt.Sprintf("now() - %s", -v.offset)
+	default:
+		return "now()"
+	}
+}
+
+func NewReferenceValue(path string) ReferenceValue {
+	return ReferenceValue{path: path}
+}
+
+// Value returns the bound document reference. as long as the value is
+// not bound to a client, the reference only carries the relative path
+func (v ReferenceValue) Value() any {
+	if v.ref != nil {
+		return v.ref
+	}
+
+	return &firestore.DocumentRef{
+		ID:   path.Base(v.path),
+		Path: v.path,
+	}
+}
+func (v ReferenceValue) String() string {
+	return fmt.Sprintf(`ref("%s")`, v.path)
+}
+func (v ReferenceValue) Bind(client *firestore.Client) Value {
+	return ReferenceValue{
+		path: v.path,
+		ref:  client.Doc(v.path),
+	}
+}
+
+func NewGeoPointValue(latitude, longitude float64) GeoPointValue {
+	return GeoPointValue{latitude: latitude, longitude: longitude}
+}
+func (v GeoPointValue) Value() any {
+	return &latlng.LatLng{Latitude: v.latitude, Longitude: v.longitude}
+}
+func (v GeoPointValue) String() string {
+	return fmt.Sprintf("geopoint(%v, %v)", v.latitude, v.longitude)
+}
+
+func NewBytesValue(value []byte) BytesValue {
+	return BytesValue{value: value}
+}
+func (v BytesValue) Value() any {
+	return v.value
+}
+func (v BytesValue) String() string {
+	return fmt.Sprintf(`bytes("%s")`, base64.StdEncoding.EncodeToString(v.value))
+}
+
+// bindValue binds value and all nested values to client
+func bindValue(client *firestore.Client, value Value) Value {
+	switch value := value.(type) {
+	case ClientValue:
+		return value.Bind(client)
+	case ArrayValue:
+		bound := NewArrayValue()
+		for _, v := range value.Values {
+			bound.Add(bindValue(client, v))
+		}
+		return bound
+	default:
+		return value
+	}
+}
+
+func (w Where) EntityFilter() firestore.EntityFilter {
+	return firestore.PropertyPathFilter{
+		Path:     w.Key.FieldPath(),
+		Operator: w.Operator.String(),
+		Value:    w.Value.Value(),
+	}
+}
+
 func (w Where) String() string {
 	return fmt.Sprintf("%s %s %s", w.Key, w.Operator.String(), w.Value.String())
 }
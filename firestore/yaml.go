@@ -0,0 +1,343 @@
+package firestore
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"gopkg.in/yaml.v3"
+)
+
+// besides the standard tags like !!timestamp and !!binary these tags
+// map yaml values onto firestore types
+const (
+	yamlTagRef      = "!ref"
+	yamlTagGeoPoint = "!geopoint"
+	yamlMergeKey    = "<<"
+)
+
+var _ yaml.Marshaler = &FirestoreDoc{}
+
+func DecodeYAMLObject(r io.Reader, options DecodeOptions) (JSONObject, error) {
+	var j JSONObject
+
+	data, err := decodeYAML(r)
+	if err != nil {
+		return j, err
+	}
+
+	if err := j.setData(data, "yaml"); err != nil {
+		return j, err
+	}
+
+	return j, j.applyOptions(options)
+}
+
+func DecodeYAMLArray(r io.Reader, options DecodeOptions) (JSONArray, error) {
+	var j JSONArray
+
+	data, err := decodeYAML(r)
+	if err != nil {
+		return j, err
+	}
+
+	if err := j.setData(data, "yaml"); err != nil {
+		return j, err
+	}
+
+	return j, j.applyOptions(options)
+}
+
+func decodeYAML(r io.Reader) (any, error) {
+	var node yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&node); err != nil {
+		return nil, err
+	}
+
+	return fromYAMLNode(&node)
+}
+
+// fromYAMLNode converts a yaml node to the values the json decoder produces.
+// timestamps, references, geopoints and bytes become their firestore types
+func fromYAMLNode(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return fromYAMLNode(node.Content[0])
+	case yaml.AliasNode:
+		return fromYAMLNode(node.Alias)
+	case yaml.MappingNode:
+		if node.Tag == yamlTagGeoPoint {
+			return fromYAMLGeoPoint(node)
+		}
+		return fromYAMLMapping(node)
+	case yaml.SequenceNode:
+		if node.Tag == yamlTagGeoPoint {
+			return fromYAMLGeoPoint(node)
+		}
+
+		values := make([]any, len(node.Content))
+		for i, item := range node.Content {
+			v, err := fromYAMLNode(item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	case yaml.ScalarNode:
+		return fromYAMLScalar(node)
+	default:
+		return nil, fmt.Errorf("line %d: unsupported yaml node", node.Line)
+	}
+}
+
+func fromYAMLMapping(node *yaml.Node) (map[string]any, error) {
+	out := make(map[string]any, len(node.Content)/2)
+
+	// merged fields don't override the fields of the mapping itself
+	var merged []map[string]any
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: keys must be strings", key.Line)
+		}
+
+		if key.Value == yamlMergeKey && key.ShortTag() == "!!merge" {
+			maps, err := fromYAMLMerge(value)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, maps...)
+			continue
+		}
+
+		v, err := fromYAMLNode(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key.Value, err)
+		}
+		out[key.Value] = v
+	}
+
+	for _, m := range merged {
+		for key, v := range m {
+			if _, found := out[key]; !found {
+				out[key] = v
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func fromYAMLMerge(node *yaml.Node) ([]map[string]any, error) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	nodes := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		nodes = node.Content
+	}
+
+	maps := make([]map[string]any, len(nodes))
+	for i, n := range nodes {
+		v, err := fromYAMLNode(n)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("line %d: only mappings can be merged", n.Line)
+		}
+		maps[i] = m
+	}
+
+	return maps, nil
+}
+
+func fromYAMLScalar(node *yaml.Node) (any, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err == nil {
+			return i, nil
+		}
+
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, fmt.Errorf("line %d: invalid number %s", node.Line, node.Value)
+		}
+		return f, nil
+	case "!!float":
+		var f float64
+		err := node.Decode(&f)
+		return f, err
+	case "!!str":
+		return node.Value, nil
+	case "!!timestamp":
+		var t time.Time
+		if err := node.Decode(&t); err != nil {
+			return nil, fmt.Errorf("line %d: invalid timestamp %s", node.Line, node.Value)
+		}
+		return t, nil
+	case "!!binary":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(node.Value), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid base64 %s", node.Line, node.Value)
+		}
+		return b, nil
+	case yamlTagRef:
+		if !IsDocumentPath(relativePath(node.Value)) {
+			return nil, fmt.Errorf("line %d: %s is no document path", node.Line, node.Value)
+		}
+		return RefPath(relativePath(node.Value)), nil
+	case yamlTagGeoPoint:
+		return fromYAMLGeoPoint(node)
+	default:
+		return nil, fmt.Errorf("line %d: unsupported tag %s", node.Line, node.Tag)
+	}
+}
+
+// fromYAMLGeoPoint accepts [lat, lng], {latitude: lat, longitude: lng} or "lat, lng"
+func fromYAMLGeoPoint(node *yaml.Node) (*latlng.LatLng, error) {
+	var coordinates []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			coordinates = append(coordinates, item.Value)
+		}
+	case yaml.MappingNode:
+		var point struct {
+			Latitude  *float64 `yaml:"latitude"`
+			Longitude *float64 `yaml:"longitude"`
+		}
+		if err := node.Decode(&point); err != nil || point.Latitude == nil || point.Longitude == nil {
+			return nil, fmt.Errorf("line %d: geopoint needs a latitude and a longitude", node.Line)
+		}
+		return &latlng.LatLng{Latitude: *point.Latitude, Longitude: *point.Longitude}, nil
+	default:
+		coordinates = strings.Split(node.Value, ",")
+	}
+
+	if len(coordinates) != 2 {
+		return nil, fmt.Errorf("line %d: geopoint needs a latitude and a longitude", node.Line)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(coordinates[0]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: invalid latitude %s", node.Line, coordinates[0])
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(coordinates[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: invalid longitude %s", node.Line, coordinates[1])
+	}
+
+	return &latlng.LatLng{Latitude: lat, Longitude: lng}, nil
+}
+
+func (d *FirestoreDoc) MarshalYAML() (any, error) {
+	data := d.Value
+	if d.options.Format == FormatTyped {
+		typed, err := toTypedFields(d.Value)
+		if err != nil {
+			return nil, err
+		}
+		data = typed
+	}
+
+	if !d.hasMeta() {
+		return toYAMLNode(data), nil
+	}
+
+	// the envelope keeps the order of the json envelope
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, column := range metaColumns {
+		node.Content = append(node.Content, toYAMLNode(column), toYAMLNode(d.metaValue(column)))
+	}
+	node.Content = append(node.Content, toYAMLNode(dataColumn), toYAMLNode(data))
+
+	return node, nil
+}
+
+// toYAMLNode encodes value with the tags fromYAMLNode understands,
+// so query results can be written back with fq set
+func toYAMLNode(value any) *yaml.Node {
+	switch value := value.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case time.Time:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: value.UTC().Format(time.RFC3339Nano)}
+	case []byte:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: base64.StdEncoding.EncodeToString(value)}
+	case *firestore.DocumentRef:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yamlTagRef, Value: relativePath(value.Path)}
+	case RefPath:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: yamlTagRef, Value: string(value)}
+	case *latlng.LatLng:
+		return &yaml.Node{
+			Kind:  yaml.SequenceNode,
+			Tag:   yamlTagGeoPoint,
+			Style: yaml.FlowStyle,
+			Content: []*yaml.Node{
+				toYAMLNode(value.GetLatitude()),
+				toYAMLNode(value.GetLongitude()),
+			},
+		}
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return scalarYAMLNode(value)
+		}
+		// doubles keep their fraction so they are read back as doubles
+		v := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(v, ".e") {
+			v += ".0"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v}
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, v := range value {
+			node.Content = append(node.Content, toYAMLNode(v))
+		}
+		return node
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range keys {
+			node.Content = append(node.Content, toYAMLNode(key), toYAMLNode(value[key]))
+		}
+		return node
+	default:
+		return scalarYAMLNode(value)
+	}
+}
+
+func scalarYAMLNode(value any) *yaml.Node {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(value)}
+	}
+
+	return &node
+}
@@ -0,0 +1,170 @@
+package firestore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLObjectDecoding(t *testing.T) {
+	assert := assert.New(t)
+
+	data := `
+# comments are allowed
+name: Alice
+age: 30
+score: 2.5
+active: true
+nickname: null
+quoted: "2025-01-01"
+createdAt: 2025-01-01T12:00:00Z
+manager: !ref users/bob
+home: !geopoint [52.52, 13.405]
+office: !geopoint {latitude: 48.1, longitude: 11.6}
+avatar: !!binary aGVsbG8=
+tags: [admin, dev]
+`
+
+	j, err := DecodeYAMLObject(strings.NewReader(data), DecodeOptions{})
+	assert.NoError(err)
+	assert.Equal(map[string]any{
+		"name":      "Alice",
+		"age":       int64(30),
+		"score":     2.5,
+		"active":    true,
+		"nickname":  nil,
+		"quoted":    "2025-01-01",
+		"createdAt": time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		"manager":   RefPath("users/bob"),
+		"home":      &latlng.LatLng{Latitude: 52.52, Longitude: 13.405},
+		"office":    &latlng.LatLng{Latitude: 48.1, Longitude: 11.6},
+		"avatar":    []byte("hello"),
+		"tags":      []any{"admin", "dev"},
+	}, j.Value)
+}
+
+func TestYAMLArrayDecoding(t *testing.T) {
+	assert := assert.New(t)
+
+	data := `
+- &base
+  role: user
+  active: true
+- <<: *base
+  role: admin
+`
+
+	j, err := DecodeYAMLArray(strings.NewReader(data), DecodeOptions{})
+	assert.NoError(err)
+	assert.Equal([]map[string]any{
+		{"role": "user", "active": true},
+		{"role": "admin", "active": true},
+	}, j.Values)
+
+	data = `
+- id: abc
+  path: users/abc
+  createTime: 2025-01-01T00:00:00Z
+  updateTime: 2025-01-01T00:00:00Z
+  readTime: 2025-01-02T00:00:00Z
+  data:
+    name: Carol
+`
+
+	j, err = DecodeYAMLArray(strings.NewReader(data), DecodeOptions{Envelopes: true})
+	assert.NoError(err)
+	assert.Equal([]map[string]any{{"name": "Carol"}}, j.Values)
+	assert.Equal([]string{"abc"}, j.IDs)
+}
+
+func TestYAMLDecodingErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		data  string
+		array bool
+
+		expected string
+	}{
+		{data: `[a]`, expected: "expected yaml object"},
+		{data: `a: 1`, array: true, expected: "expected yaml array"},
+		{data: `[1]`, array: true, expected: "no yaml object in array at pos 1"},
+		{data: `ref: !ref users`, expected: "users is no document path"},
+		{data: `geo: !geopoint [1]`, expected: "geopoint needs a latitude and a longitude"},
+		{data: `geo: !geopoint [a, 1]`, expected: "invalid latitude a"},
+		{data: `x: !custom 1`, expected: "unsupported tag !custom"},
+		{data: `a: [1`, expected: "yaml"},
+	}
+
+	for _, fixture := range fixtures {
+		var err error
+		if fixture.array {
+			_, err = DecodeYAMLArray(strings.NewReader(fixture.data), DecodeOptions{})
+		} else {
+			_, err = DecodeYAMLObject(strings.NewReader(fixture.data), DecodeOptions{})
+		}
+		assert.ErrorContains(err, fixture.expected, fixture.data)
+	}
+}
+
+func TestFirestoreDocMarshalYAML(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	doc := NewFirestoreDoc(map[string]any{
+		"name":      "Alice",
+		"date":      "2025-01-01",
+		"count":     int64(3),
+		"ratio":     float64(1),
+		"createdAt": ts,
+		"manager":   &firestore.DocumentRef{Path: "projects/p/databases/(default)/documents/users/bob"},
+		"home":      &latlng.LatLng{Latitude: 52.52, Longitude: 13.405},
+		"tags":      []any{"admin"},
+	})
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	assert.NoError(enc.Encode(doc))
+	assert.Equal(""+
+		"count: 3\n"+
+		"createdAt: 2025-01-01T12:00:00Z\n"+
+		"date: \"2025-01-01\"\n"+
+		"home: !geopoint [52.52, 13.405]\n"+
+		"manager: !ref users/bob\n"+
+		"name: Alice\n"+
+		"ratio: 1.0\n"+
+		"tags:\n"+
+		"  - admin\n", b.String())
+
+	// the output can be read back
+	j, err := DecodeYAMLObject(&b, DecodeOptions{})
+	assert.NoError(err)
+	assert.Equal(ts, j.Value["createdAt"])
+	assert.Equal(RefPath("users/bob"), j.Value["manager"])
+	assert.Equal(float64(1), j.Value["ratio"])
+	assert.Equal("2025-01-01", j.Value["date"])
+
+	doc = NewFirestoreDoc(map[string]any{"name": "Alice"})
+	doc.Meta = &DocMeta{ID: "abc", Path: "users/abc", CreateTime: ts, UpdateTime: ts, ReadTime: ts}
+	doc.options.WithMeta = true
+
+	b.Reset()
+	enc = yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	assert.NoError(enc.Encode(doc))
+	assert.Equal(""+
+		"id: abc\n"+
+		"path: users/abc\n"+
+		"createTime: 2025-01-01T12:00:00Z\n"+
+		"updateTime: 2025-01-01T12:00:00Z\n"+
+		"readTime: 2025-01-01T12:00:00Z\n"+
+		"data:\n"+
+		"  name: Alice\n", b.String())
+}
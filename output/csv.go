@@ -0,0 +1,33 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes rows as comma separated values with a header line.
+// use '\t' as comma for tab separated values. null values are empty cells
+func WriteCSV(w io.Writer, comma rune, columns []string, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	err := cw.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = formatValue(v)
+		}
+
+		err = cw.Write(cells)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
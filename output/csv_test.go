@@ -0,0 +1,32 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := [][]any{
+		{"Alice", int64(30), ts, `["admin","dev"]`},
+		{"Bob, Jr.", nil, nil, "multi\nline"},
+	}
+
+	var b bytes.Buffer
+	err := WriteCSV(&b, ',', []string{"name", "age", "createdAt", "tags"}, rows)
+	assert.NoError(err)
+	assert.Equal(""+
+		"name,age,createdAt,tags\n"+
+		"Alice,30,2025-01-01T12:00:00Z,\"[\"\"admin\"\",\"\"dev\"\"]\"\n"+
+		"\"Bob, Jr.\",,,\"multi\nline\"\n", b.String())
+
+	b.Reset()
+	err = WriteCSV(&b, '\t', []string{"name", "age"}, [][]any{{"Bob, Jr.", 2.5}})
+	assert.NoError(err)
+	assert.Equal("name\tage\nBob, Jr.\t2.5\n", b.String())
+}
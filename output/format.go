@@ -0,0 +1,71 @@
+package output
+
+import "fmt"
+
+// Format is how query results are printed
+type Format int
+
+const (
+	FormatJSON Format = iota + 1
+	// FormatNDJSON prints one json document per line as soon as it is loaded
+	FormatNDJSON
+	// FormatTable prints aligned columns with a header line
+	FormatTable
+	// FormatCSV prints comma separated values with nested fields flattened
+	FormatCSV
+	// FormatTSV prints tab separated values with nested fields flattened
+	FormatTSV
+	// FormatYAML prints a yaml document with tags for firestore types
+	FormatYAML
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch format {
+	case "", "json":
+		return FormatJSON, nil
+	case "ndjson":
+		return FormatNDJSON, nil
+	case "table":
+		return FormatTable, nil
+	case "csv":
+		return FormatCSV, nil
+	case "tsv":
+		return FormatTSV, nil
+	case "yaml":
+		return FormatYAML, nil
+	default:
+		return Format(0), fmt.Errorf("unknown output %s", format)
+	}
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatNDJSON:
+		return "ndjson"
+	case FormatTable:
+		return "table"
+	case FormatCSV:
+		return "csv"
+	case FormatTSV:
+		return "tsv"
+	case FormatYAML:
+		return "yaml"
+	default:
+		return ""
+	}
+}
+
+// IsDelimited reports whether f is csv or tsv
+func (f Format) IsDelimited() bool {
+	return f == FormatCSV || f == FormatTSV
+}
+
+// Comma returns the separator of delimited formats
+func (f Format) Comma() rune {
+	if f == FormatTSV {
+		return '\t'
+	}
+	return ','
+}
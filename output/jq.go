@@ -0,0 +1,123 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/itchyny/gojq"
+)
+
+// JQ is a compiled jq expression
+type JQ struct {
+	code *gojq.Code
+}
+
+func ParseJQ(expr string) (*JQ, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JQ{code: code}, nil
+}
+
+// Run returns all results of the expression for the json encoding of v
+func (q *JQ) Run(v any) ([]any, error) {
+	input, err := toJQValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []any
+	iter := q.code.Run(input)
+	for {
+		result, ok := iter.Next()
+		if !ok {
+			return results, nil
+		}
+
+		if err, ok := result.(error); ok {
+			// halt stops without an error
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				return results, nil
+			}
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+}
+
+// EncodeJQResults encodes every result on its own line like jq. with raw
+// strings are printed without quotes
+func EncodeJQResults(results []any, options JSONOptions, raw bool) ([]byte, error) {
+	var out bytes.Buffer
+	for _, result := range results {
+		if s, ok := result.(string); ok && raw {
+			out.WriteString(s)
+			out.WriteByte('\n')
+			continue
+		}
+
+		b, err := gojq.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Write(formatJSON(b, options))
+		if !options.Pretty {
+			out.WriteByte('\n')
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// toJQValue converts v to the types gojq works with by encoding it as
+// json first. whole numbers stay integers
+func toJQValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var out any
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+
+	return normalizeJQNumbers(out), nil
+}
+
+func normalizeJQNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeJQNumbers(item)
+		}
+		return v
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeJQNumbers(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJQ(t *testing.T) {
+	assert := assert.New(t)
+
+	docs := []any{
+		map[string]any{"name": "Alice", "age": int64(9007199254740993), "tags": []any{"admin"}},
+		map[string]any{"name": "Bob", "age": 2.5, "tags": []any{}},
+	}
+
+	fixtures := []struct {
+		expr    string
+		raw     bool
+		options JSONOptions
+
+		expected string
+	}{
+		{expr: ".[].name", expected: "\"Alice\"\n\"Bob\"\n"},
+		{expr: ".[].name", raw: true, expected: "Alice\nBob\n"},
+		{expr: ".[0].age", expected: "9007199254740993\n"},
+		{expr: "map(.age)", raw: true, expected: "[9007199254740993,2.5]\n"},
+		{expr: "[.[] | select(.tags | length > 0) | .name]", expected: "[\"Alice\"]\n"},
+		{expr: ".[1] | {name}", options: JSONOptions{Pretty: true}, expected: "{\n  \"name\": \"Bob\"\n}\n"},
+		{expr: "empty", expected: ""},
+		{expr: "halt", expected: ""},
+	}
+
+	for _, fixture := range fixtures {
+		jq, err := ParseJQ(fixture.expr)
+		if !assert.NoError(err, fixture.expr) {
+			continue
+		}
+
+		results, err := jq.Run(docs)
+		if !assert.NoError(err, fixture.expr) {
+			continue
+		}
+
+		b, err := EncodeJQResults(results, fixture.options, fixture.raw)
+		assert.NoError(err, fixture.expr)
+		assert.Equal(fixture.expected, string(b), fixture.expr)
+	}
+}
+
+func TestJQErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseJQ(".[")
+	assert.Error(err)
+
+	jq, err := ParseJQ(`error("boom")`)
+	assert.NoError(err)
+	_, err = jq.Run(nil)
+	assert.ErrorContains(err, "boom")
+}
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONOptions controls how json is printed for humans
+type JSONOptions struct {
+	// Pretty indents the json by 2 spaces and ends it with a newline
+	Pretty bool
+	// Color highlights keys, strings, numbers and literals with ansi colors
+	Color bool
+}
+
+const (
+	colorReset  = "\033[0m"
+	colorKey    = "\033[34;1m"
+	colorString = "\033[32m"
+	colorNumber = "\033[36m"
+	colorBool   = "\033[33m"
+	colorNull   = "\033[90m"
+)
+
+// EncodeJSON encodes v as compact json or formatted for terminals
+func EncodeJSON(v any, options JSONOptions) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatJSON(b, options), nil
+}
+
+// formatJSON indents and colors compact json
+func formatJSON(b []byte, options JSONOptions) []byte {
+	if options.Pretty {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, b, "", "  "); err == nil {
+			b = indented.Bytes()
+		}
+	}
+
+	if options.Color {
+		b = colorizeJSON(b)
+	}
+	if options.Pretty {
+		b = append(b, '\n')
+	}
+
+	return b
+}
+
+// colorizeJSON wraps the tokens of valid json in ansi colors.
+// punctuation and whitespace are kept as they are
+func colorizeJSON(b []byte) []byte {
+	var out bytes.Buffer
+
+	for i := 0; i < len(b); {
+		c := b[i]
+
+		var end int
+		var color string
+		switch {
+		case c == '"':
+			end = stringEnd(b, i)
+			color = colorString
+
+			next := end
+			for next < len(b) && isJSONSpace(b[next]) {
+				next++
+			}
+			if next < len(b) && b[next] == ':' {
+				color = colorKey
+			}
+		case c == '-' || c >= '0' && c <= '9':
+			end = i + 1
+			for end < len(b) && bytes.IndexByte([]byte("0123456789+-.eE"), b[end]) >= 0 {
+				end++
+			}
+			color = colorNumber
+		case bytes.HasPrefix(b[i:], []byte("true")):
+			end, color = i+4, colorBool
+		case bytes.HasPrefix(b[i:], []byte("false")):
+			end, color = i+5, colorBool
+		case bytes.HasPrefix(b[i:], []byte("null")):
+			end, color = i+4, colorNull
+		default:
+			out.WriteByte(c)
+			i++
+			continue
+		}
+
+		out.WriteString(color)
+		out.Write(b[i:end])
+		out.WriteString(colorReset)
+		i = end
+	}
+
+	return out.Bytes()
+}
+
+// stringEnd returns the index after the closing quote of the string at start
+func stringEnd(b []byte, start int) int {
+	for i := start + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+
+	return len(b)
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
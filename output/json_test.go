@@ -0,0 +1,50 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	value := map[string]any{"name": "a\"b: c", "age": int64(-3), "ok": true, "tags": []any{nil, 1.5e3}}
+
+	fixtures := []struct {
+		options JSONOptions
+
+		expected string
+	}{
+		{
+			options:  JSONOptions{},
+			expected: `{"age":-3,"name":"a\"b: c","ok":true,"tags":[null,1500]}`,
+		},
+		{
+			options: JSONOptions{Pretty: true},
+			expected: "{\n" +
+				"  \"age\": -3,\n" +
+				"  \"name\": \"a\\\"b: c\",\n" +
+				"  \"ok\": true,\n" +
+				"  \"tags\": [\n" +
+				"    null,\n" +
+				"    1500\n" +
+				"  ]\n" +
+				"}\n",
+		},
+		{
+			options: JSONOptions{Color: true},
+			expected: "{" +
+				colorKey + `"age"` + colorReset + ":" + colorNumber + "-3" + colorReset + "," +
+				colorKey + `"name"` + colorReset + ":" + colorString + `"a\"b: c"` + colorReset + "," +
+				colorKey + `"ok"` + colorReset + ":" + colorBool + "true" + colorReset + "," +
+				colorKey + `"tags"` + colorReset + ":[" + colorNull + "null" + colorReset + "," + colorNumber + "1500" + colorReset + "]}",
+		},
+	}
+
+	for _, fixture := range fixtures {
+		b, err := EncodeJSON(value, fixture.options)
+		assert.NoError(err)
+		assert.Equal(fixture.expected, string(b), "%+v", fixture.options)
+	}
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	tablePadding = 2
+	// minColumnWidth is the width columns are truncated to at most
+	minColumnWidth = 6
+	ellipsis       = "…"
+)
+
+// WriteTable writes rows as aligned columns with a header line. if maxWidth
+// is greater than 0 the widest columns are truncated until lines fit
+func WriteTable(w io.Writer, columns []string, rows [][]any, maxWidth int) error {
+	lines := make([][]string, 0, len(rows)+1)
+	lines = append(lines, columns)
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = FormatCell(v)
+		}
+		lines = append(lines, cells)
+	}
+
+	widths := make([]int, len(columns))
+	for _, line := range lines {
+		for i, cell := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	if maxWidth > 0 {
+		fitWidths(widths, maxWidth-tablePadding*(len(widths)-1))
+	}
+
+	for _, line := range lines {
+		var b strings.Builder
+		for i, cell := range line {
+			cell = truncate(cell, widths[i])
+			b.WriteString(cell)
+
+			if i < len(line)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+tablePadding))
+			}
+		}
+		b.WriteString("\n")
+
+		_, err := io.WriteString(w, b.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fitWidths shrinks the widest column until all widths add up to available
+// or every column is at its minimum width
+func fitWidths(widths []int, available int) {
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+
+	for total > available {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			return
+		}
+
+		widths[widest]--
+		total--
+	}
+}
+
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:width-1]) + ellipsis
+}
+
+// FormatCell formats a single value for tabular output. strings are
+// printed without quotes, maps and arrays as json
+func FormatCell(v any) string {
+	if v == nil {
+		return "null"
+	}
+
+	return sanitizeCell(formatValue(v))
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// sanitizeCell replaces characters which would break the table layout
+func sanitizeCell(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
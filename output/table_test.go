@@ -0,0 +1,68 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTable(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	err := WriteTable(&b, []string{"status", "count"}, [][]any{
+		{"open", int64(12)},
+		{"in progress", int64(3)},
+		{nil, int64(1)},
+	}, 0)
+	assert.NoError(err)
+
+	assert.Equal(""+
+		"status       count\n"+
+		"open         12\n"+
+		"in progress  3\n"+
+		"null         1\n", b.String())
+}
+
+func TestWriteTableTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	err := WriteTable(&b, []string{"id", "name", "bio"}, [][]any{
+		{"abc", "Alice", "likes long walks on the beach"},
+		{"def", "Bob", nil},
+	}, 30)
+	assert.NoError(err)
+
+	assert.Equal(""+
+		"id   name   bio\n"+
+		"abc  Alice  likes long walks …\n"+
+		"def  Bob    null\n", b.String())
+}
+
+func TestFormatCell(t *testing.T) {
+	assert := assert.New(t)
+
+	fixtures := []struct {
+		value any
+
+		expected string
+	}{
+		{value: nil, expected: "null"},
+		{value: "foo", expected: "foo"},
+		{value: "a\tb\nc", expected: "a b c"},
+		{value: true, expected: "true"},
+		{value: int64(42), expected: "42"},
+		{value: 2.5, expected: "2.5"},
+		{value: 1e21, expected: "1000000000000000000000"},
+		{value: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), expected: "2025-01-01T12:00:00Z"},
+		{value: []any{int64(1), "a"}, expected: `[1,"a"]`},
+		{value: map[string]any{"a": int64(1)}, expected: `{"a":1}`},
+	}
+
+	for _, fixture := range fixtures {
+		assert.Equal(fixture.expected, FormatCell(fixture.value))
+	}
+}
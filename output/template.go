@@ -0,0 +1,85 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template renders one line per value
+type Template struct {
+	tmpl *template.Template
+	// newline is false if the template ends with a newline itself
+	newline bool
+}
+
+var templateFuncs = template.FuncMap{
+	"json":       templateJSON,
+	"formatTime": templateFormatTime,
+	"default":    templateDefault,
+}
+
+func ParseTemplate(text string) (*Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{
+		tmpl:    tmpl,
+		newline: !strings.HasSuffix(text, "\n"),
+	}, nil
+}
+
+func (t *Template) Execute(w io.Writer, data any) error {
+	err := t.tmpl.Execute(w, data)
+	if err != nil {
+		return err
+	}
+
+	if t.newline {
+		_, err = io.WriteString(w, "\n")
+	}
+	return err
+}
+
+func templateJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// templateFormatTime formats a timestamp in utc with a go layout like 2006-01-02.
+// strings are parsed as rfc3339 first
+func templateFormatTime(layout string, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		return v.UTC().Format(layout), nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return "", fmt.Errorf("formatTime: %s is no timestamp", v)
+		}
+		return t.UTC().Format(layout), nil
+	default:
+		return "", fmt.Errorf("formatTime: unsupported type %T", v)
+	}
+}
+
+// templateDefault returns def if v is missing, null or an empty string,
+// e.g. {{.data.name | default "unknown"}}
+func templateDefault(def any, v any) any {
+	if v == nil || v == "" {
+		return def
+	}
+
+	return v
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	data := map[string]any{
+		"id": "abc",
+		"data": map[string]any{
+			"email":     "alice@example.com",
+			"createdAt": time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC),
+			"tags":      []any{"admin", "dev"},
+			"empty":     "",
+		},
+	}
+
+	fixtures := []struct {
+		template string
+
+		expected string
+	}{
+		{template: "{{.id}} {{.data.email}}", expected: "abc alice@example.com\n"},
+		{template: "{{.id}}\n", expected: "abc\n"},
+		{template: "{{json .data.tags}}", expected: "[\"admin\",\"dev\"]\n"},
+		{template: `{{formatTime "2006-01-02" .data.createdAt}}`, expected: "2025-01-02\n"},
+		{template: `{{.data.createdAt | formatTime "15:04"}}`, expected: "12:00\n"},
+		{template: `{{formatTime "2006" "2024-05-01T00:00:00Z"}}`, expected: "2024\n"},
+		{template: `{{.data.missing | default "n/a"}} {{.data.empty | default "-"}} {{.data.email | default "-"}}`, expected: "n/a - alice@example.com\n"},
+	}
+
+	for _, fixture := range fixtures {
+		tmpl, err := ParseTemplate(fixture.template)
+		if !assert.NoError(err, fixture.template) {
+			continue
+		}
+
+		var b bytes.Buffer
+		assert.NoError(tmpl.Execute(&b, data), fixture.template)
+		assert.Equal(fixture.expected, b.String(), fixture.template)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParseTemplate("{{.id")
+	assert.Error(err)
+
+	tmpl, err := ParseTemplate(`{{formatTime "2006" .id}}`)
+	assert.NoError(err)
+	err = tmpl.Execute(&bytes.Buffer{}, map[string]any{"id": "abc"})
+	assert.ErrorContains(err, "abc is no timestamp")
+}
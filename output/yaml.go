@@ -0,0 +1,20 @@
+package output
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WriteYAML writes v as a yaml document indented by 2 spaces
+func WriteYAML(w io.Writer, v any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
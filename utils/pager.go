@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultPager is used if PAGER isn't set. it keeps colors and
+// quits right away if the output fits on the screen
+const defaultPager = "less -FRX"
+
+// Page writes b to stdout. if b doesn't fit on the terminal it is shown with
+// $PAGER instead. an empty PAGER disables paging
+func Page(b []byte) error {
+	height := StdoutHeight()
+	if height == 0 || bytes.Count(b, []byte("\n")) < height {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	pager, found := os.LookupEnv("PAGER")
+	if !found {
+		pager = defaultPager
+	}
+
+	args := strings.Fields(pager)
+	if len(args) == 0 {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -1,7 +1,43 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
 
 func ClearLine() {
 	fmt.Print("\033[2K\r")
 }
+
+func IsStdoutTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// StdoutWidth returns the number of columns of the terminal.
+// it is 0 if stdout isn't a terminal
+func StdoutWidth() int {
+	width, _ := stdoutSize()
+	return width
+}
+
+// StdoutHeight returns the number of rows of the terminal.
+// it is 0 if stdout isn't a terminal
+func StdoutHeight() int {
+	_, height := stdoutSize()
+	return height
+}
+
+func stdoutSize() (int, int) {
+	if !IsStdoutTerminal() {
+		return 0, 0
+	}
+
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0, 0
+	}
+
+	return width, height
+}